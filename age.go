@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	labelAgePrefix  = "age: "
+	oldestReportMax = 10
+	day             = 24 * time.Hour
+	noArea          = "(none)"
+)
+
+type ageBucket struct {
+	name   string
+	maxAge time.Duration
+}
+
+// buckets are ordered by age, last one has no upper bound
+var ageBuckets = []ageBucket{
+	{name: "<1w", maxAge: 7 * day},
+	{name: "<1m", maxAge: 30 * day},
+	{name: "<3m", maxAge: 91 * day},
+	{name: "<6m", maxAge: 182 * day},
+	{name: "<1y", maxAge: 365 * day},
+	{name: ">1y", maxAge: 0},
+}
+
+func ageBucketName(age time.Duration) string {
+	for _, b := range ageBuckets {
+		if b.maxAge == 0 || age < b.maxAge {
+			return b.name
+		}
+	}
+
+	return ageBuckets[len(ageBuckets)-1].name
+}
+
+func formatAge(age time.Duration) string {
+	days := int(age / day)
+	switch {
+	case days >= 365:
+		return fmt.Sprintf("%.1fy", float64(days)/365)
+	case days >= 30:
+		return fmt.Sprintf("%vmo", days/30)
+	default:
+		return fmt.Sprintf("%vd", days)
+	}
+}
+
+// age returns how long ago the comment was written and false if it is unknown
+func (s *service) age(c *tdglib.ToDoComment) (time.Duration, bool) {
+	info, ok := s.blames[c]
+	if !ok || info.authorTime.IsZero() {
+		return 0, false
+	}
+
+	return s.now.Sub(info.authorTime), true
+}
+
+func (s *service) ageLabel(c *tdglib.ToDoComment) (string, bool) {
+	// blame is also used for assignees and relative dates, but age
+	// labels are only kept up to date by AGE_REPORT
+	if !s.env.ageReport {
+		return "", false
+	}
+
+	age, ok := s.age(c)
+	if !ok {
+		return "", false
+	}
+
+	return labelAgePrefix + ageBucketName(age), true
+}
+
+// updateAgeLabels moves open issues to the new age bucket as their comments get older
+func (s *service) updateAgeLabels(issueMap map[string]*github.Issue, comments []*tdglib.ToDoComment) {
+	defer s.wg.Done()
+
+	count := 0
+	for _, c := range comments {
+		i, ok := issueMap[c.Title]
+		if !ok || i.GetState() == "closed" {
+			continue
+		}
+
+		label, ok := s.ageLabel(c)
+		if !ok {
+			continue
+		}
+
+		labels := make([]string, 0, len(i.Labels)+1)
+		hasLabel := false
+		for _, l := range i.Labels {
+			name := l.GetName()
+			if name == label {
+				hasLabel = true
+			}
+			if !strings.HasPrefix(name, labelAgePrefix) || name == label {
+				labels = append(labels, name)
+			}
+		}
+
+		if hasLabel && len(labels) == len(i.Labels) {
+			continue
+		}
+
+		if !hasLabel {
+			labels = append(labels, label)
+		}
+
+		log.Printf("About to update age label. issue=%v label=%v", i.GetNumber(), label)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		req := &github.IssueRequest{Labels: &labels}
+		edited, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), req)
+		if err != nil {
+			log.Printf("Error while updating age label. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		// keep labels up to date for the following steps
+		i.Labels = edited.Labels
+
+		count++
+	}
+
+	log.Printf("Updated age labels. count=%v", count)
+}
+
+func (s *service) reportAges(comments []*tdglib.ToDoComment) {
+	aged := make([]*tdglib.ToDoComment, 0, len(comments))
+	histogram := make(map[string]map[string]int)
+
+	for _, c := range comments {
+		age, ok := s.age(c)
+		if !ok {
+			continue
+		}
+
+		aged = append(aged, c)
+
+		area := c.Category
+		if len(area) == 0 {
+			area = noArea
+		}
+
+		if _, ok := histogram[area]; !ok {
+			histogram[area] = make(map[string]int)
+		}
+		histogram[area][ageBucketName(age)]++
+	}
+
+	if len(aged) == 0 {
+		return
+	}
+
+	sort.SliceStable(aged, func(i, j int) bool {
+		return s.blames[aged[i]].authorTime.Before(s.blames[aged[j]].authorTime)
+	})
+
+	var b strings.Builder
+	b.WriteString("| Age | Type | Title | Location |\n|---|---|---|---|\n")
+	for i, c := range aged {
+		if i >= oldestReportMax {
+			break
+		}
+		age, _ := s.age(c)
+		fmt.Fprintf(&b, "| %s | %s | %s | [%s:%v](%s) |\n",
+			formatAge(age), c.Type, markdownEscape(c.Title), markdownEscape(c.File), c.Line, s.createFileLink(c))
+	}
+	s.report.addSection("Oldest TODOs", b.String())
+
+	areas := make([]string, 0, len(histogram))
+	for area := range histogram {
+		areas = append(areas, area)
+	}
+	sort.Strings(areas)
+
+	b.Reset()
+	b.WriteString("| Area |")
+	for _, bucket := range ageBuckets {
+		fmt.Fprintf(&b, " %s |", bucket.name)
+	}
+	b.WriteString("\n|---|" + strings.Repeat("---|", len(ageBuckets)) + "\n")
+	for _, area := range areas {
+		fmt.Fprintf(&b, "| %s |", markdownEscape(area))
+		for _, bucket := range ageBuckets {
+			fmt.Fprintf(&b, " %v |", histogram[area][bucket.name])
+		}
+		b.WriteString("\n")
+	}
+	s.report.addSection("TODO age by area", b.String())
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestAgeBucketName(t *testing.T) {
+	cases := []struct {
+		age  time.Duration
+		want string
+	}{
+		{age: time.Hour, want: "<1w"},
+		{age: 10 * day, want: "<1m"},
+		{age: 60 * day, want: "<3m"},
+		{age: 100 * day, want: "<6m"},
+		{age: 200 * day, want: "<1y"},
+		{age: 400 * day, want: ">1y"},
+	}
+
+	for _, tc := range cases {
+		if got := ageBucketName(tc.age); got != tc.want {
+			t.Fatalf("ageBucketName(%v) = %q, want %q", tc.age, got, tc.want)
+		}
+	}
+}
+
+func TestReportAges(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := &tdglib.ToDoComment{Type: "TODO", Title: "Old one", File: "a.go", Line: 1, Category: "api"}
+	recent := &tdglib.ToDoComment{Type: "BUG", Title: "Recent one", File: "b.go", Line: 2}
+	unknown := &tdglib.ToDoComment{Type: "TODO", Title: "Unknown", File: "c.go", Line: 3}
+
+	s := &service{
+		env:    &env{codeOwner: "o", codeRepo: "r", sha: "sha"},
+		tdg:    tdglib.NewToDoGenerator(".", nil, nil, false, 0, 0, 1),
+		report: &report{},
+		now:    now,
+		blames: map[*tdglib.ToDoComment]*blameInfo{
+			old:    {authorTime: now.Add(-400 * day)},
+			recent: {authorTime: now.Add(-2 * day)},
+		},
+	}
+
+	if _, ok := s.ageLabel(old); ok {
+		t.Fatalf("ageLabel() is known without AGE_REPORT")
+	}
+
+	s.env.ageReport = true
+
+	if label, ok := s.ageLabel(old); !ok || label != "age: >1y" {
+		t.Fatalf("ageLabel() = %q, %v", label, ok)
+	}
+
+	if _, ok := s.ageLabel(unknown); ok {
+		t.Fatalf("ageLabel() is known for comment without blame")
+	}
+
+	s.reportAges([]*tdglib.ToDoComment{recent, unknown, old})
+	summary := s.report.String()
+
+	if strings.Index(summary, "Old one") > strings.Index(summary, "Recent one") {
+		t.Fatalf("reportAges() does not list oldest comments first:\n%s", summary)
+	}
+
+	for _, want := range []string{"| api | 0 | 0 | 0 | 0 | 0 | 1 |", "| (none) | 1 | 0 | 0 | 0 | 0 | 0 |"} {
+		if !strings.Contains(summary, want) {
+			t.Fatalf("reportAges() does not contain %q:\n%s", want, summary)
+		}
+	}
+
+	if strings.Contains(summary, "Unknown") {
+		t.Fatalf("reportAges() contains comment without blame:\n%s", summary)
+	}
+}
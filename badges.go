@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	badgeTypeFilePrefix  = "type-"
+	badgeStyleFlat       = "flat"
+	badgeStyleFlatSquare = "flat-square"
+	defaultBadgeColor    = "#007ec6"
+	badgeLabelColor      = "#555"
+	badgeHeight          = 20
+	badgeCharWidth       = 7
+	badgeTextPadding     = 10
+)
+
+// keep zero badges for all default types so that links in READMEs never break
+var badgeTypes = []string{"TODO", "FIXME", "BUG", "HACK"}
+
+type badge struct {
+	file  string
+	label string
+	value string
+}
+
+func badgeTextWidth(s string) int {
+	return len([]rune(s))*badgeCharWidth + badgeTextPadding
+}
+
+func renderBadge(label, value, color, style string) []byte {
+	labelWidth := badgeTextWidth(label)
+	valueWidth := badgeTextWidth(value)
+	width := labelWidth + valueWidth
+	label = html.EscapeString(label)
+	value = html.EscapeString(value)
+
+	radius := 3
+	if style == badgeStyleFlatSquare {
+		radius = 0
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s: %s">`+"\n",
+		width, badgeHeight, label, value)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`+"\n", label, value)
+	if style == badgeStyleFlat {
+		b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` + "\n")
+	}
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="%d" rx="%d" fill="#fff"/></clipPath>`+"\n", width, badgeHeight, radius)
+	b.WriteString(`<g clip-path="url(#r)">` + "\n")
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`+"\n", labelWidth, badgeHeight, badgeLabelColor)
+	fmt.Fprintf(&b, `<rect x="%d" width="%d" height="%d" fill="%s"/>`+"\n", labelWidth, valueWidth, badgeHeight, html.EscapeString(color))
+	if style == badgeStyleFlat {
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="url(#s)"/>`+"\n", width, badgeHeight)
+	}
+	b.WriteString("</g>\n")
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` + "\n")
+	fmt.Fprintf(&b, `<text x="%d" y="14">%s</text>`+"\n", labelWidth/2, label)
+	fmt.Fprintf(&b, `<text x="%d" y="14">%s</text>`+"\n", labelWidth+valueWidth/2, value)
+	b.WriteString("</g>\n</svg>\n")
+
+	return b.Bytes()
+}
+
+func newBadges(comments []*tdglib.ToDoComment) []*badge {
+	counts := make(map[string]int)
+	types := append([]string{}, badgeTypes...)
+	estimate := 0.0
+
+	for _, c := range comments {
+		t := strings.ToUpper(c.Type)
+		if _, ok := counts[t]; !ok && !containsString(badgeTypes, t) {
+			types = append(types, t)
+		}
+		counts[t]++
+		estimate += c.Estimate
+	}
+
+	// per type files are prefixed so that types like TOTAL or DEBT do not clash
+	badges := []*badge{{file: "total.svg", label: "TODO comments", value: fmt.Sprintf("%v", len(comments))}}
+	for _, t := range types {
+		file := badgeTypeFilePrefix + strings.ToLower(t) + ".svg"
+		badges = append(badges, &badge{file: file, label: t + "s", value: fmt.Sprintf("%v", counts[t])})
+	}
+
+	debt := "0h"
+	if estimate > minEstimate {
+		debt = formatEstimate(math.Round(estimate*10) / 10)
+	}
+	badges = append(badges, &badge{file: "debt.svg", label: "debt", value: debt})
+
+	return badges
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *env) writeBadges(comments []*tdglib.ToDoComment) error {
+	if err := os.MkdirAll(e.badgesDir, 0755); err != nil {
+		return err
+	}
+
+	badges := newBadges(comments)
+	for _, b := range badges {
+		path := filepath.Join(e.badgesDir, b.file)
+		if err := os.WriteFile(path, renderBadge(b.label, b.value, e.badgeColor, e.badgeStyle), 0644); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Wrote badges. dir=%v count=%v", e.badgesDir, len(badges))
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestNewBadges(t *testing.T) {
+	comments := []*tdglib.ToDoComment{
+		{Type: "TODO", Estimate: 30},
+		{Type: "TODO", Estimate: 6},
+		{Type: "FIXME"},
+		{Type: "DEBT"},
+	}
+
+	got := make(map[string]string)
+	for _, b := range newBadges(comments) {
+		got[b.file] = b.label + ": " + b.value
+	}
+
+	want := map[string]string{
+		"total.svg":      "TODO comments: 4",
+		"type-todo.svg":  "TODOs: 2",
+		"type-fixme.svg": "FIXMEs: 1",
+		"type-bug.svg":   "BUGs: 0",
+		"type-hack.svg":  "HACKs: 0",
+		"type-debt.svg":  "DEBTs: 1",
+		"debt.svg":       "debt: 36h",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("newBadges() = %v, want %v", got, want)
+	}
+
+	for file, w := range want {
+		if got[file] != w {
+			t.Fatalf("newBadges()[%v] = %q, want %q", file, got[file], w)
+		}
+	}
+}
+
+func TestRenderBadgeStyle(t *testing.T) {
+	flat := renderBadge("debt", "36h", defaultBadgeColor, badgeStyleFlat)
+	square := renderBadge("debt", "36h", defaultBadgeColor, badgeStyleFlatSquare)
+
+	if !bytes.Contains(flat, []byte(`rx="3"`)) || !bytes.Contains(flat, []byte("linearGradient")) {
+		t.Fatalf("renderBadge() flat style is not rounded")
+	}
+
+	if !bytes.Contains(square, []byte(`rx="0"`)) || bytes.Contains(square, []byte("linearGradient")) {
+		t.Fatalf("renderBadge() flat-square style is rounded")
+	}
+
+	if !bytes.Contains(flat, []byte(">36h</text>")) {
+		t.Fatalf("renderBadge() does not contain the value")
+	}
+}
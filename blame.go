@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+type blameInfo struct {
+	commitHash     string
+	authorEmail    string
+	committerEmail string
+	authorTime     time.Time
+	committerTime  time.Time
+	boundary       bool
+}
+
+func trimEmail(s string) string {
+	s = strings.TrimPrefix(s, "<")
+	return strings.TrimSuffix(s, ">")
+}
+
+func parseUnixTime(s string) time.Time {
+	seconds, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0).UTC()
+}
+
+// parseBlamePorcelain parses output of "git blame --porcelain" into
+// a map from the final line number to the details of the commit
+func parseBlamePorcelain(out []byte) map[int]*blameInfo {
+	result := make(map[int]*blameInfo)
+	commits := make(map[string]*blameInfo)
+
+	var current *blameInfo
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// actual content of the line
+		if strings.HasPrefix(line, "\t") {
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			// header "<sha> <original line> <final line> [<lines in group>]"
+			parts := strings.Fields(line)
+			if len(parts) < 3 {
+				continue
+			}
+
+			finalLine, err := strconv.Atoi(parts[2])
+			if err != nil {
+				continue
+			}
+
+			info, ok := commits[parts[0]]
+			if !ok {
+				info = &blameInfo{commitHash: parts[0]}
+				commits[parts[0]] = info
+			}
+
+			current = info
+			result[finalLine] = info
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author-mail":
+			current.authorEmail = trimEmail(value)
+		case "author-time":
+			current.authorTime = parseUnixTime(value)
+		case "committer-mail":
+			current.committerEmail = trimEmail(value)
+		case "committer-time":
+			current.committerTime = parseUnixTime(value)
+		case "boundary":
+			current.boundary = true
+		}
+	}
+
+	return result
+}
+
+// blameLines runs a single git blame for all requested lines of the file
+func blameLines(root, file string, lines []int) (map[int]*blameInfo, error) {
+	args := []string{"blame", "--porcelain"}
+	for _, l := range lines {
+		lineNumber := strconv.Itoa(l)
+		args = append(args, "-L", lineNumber+","+lineNumber)
+	}
+	args = append(args, "--", file)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseBlamePorcelain(out), nil
+}
+
+func blameLine(root, file string, line int) (*blameInfo, error) {
+	blames, err := blameLines(root, file, []int{line})
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := blames[line]
+	if !ok {
+		return nil, fmt.Errorf("no blame details for line %v", line)
+	}
+
+	return info, nil
+}
+
+// markSafeDirectory allows git to run in the checkout owned by another user
+func markSafeDirectory(root string) {
+	root = strings.TrimSuffix(filepath.Clean(root), "/")
+	cmd := exec.Command("git", "config", "--global", "--add", "safe.directory", root)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Printf("Error while marking directory as safe. root=%v err=%v out=%s", root, err, out)
+	}
+}
+
+func (s *service) markSafeDirectory() {
+	s.safeDirectoryOnce.Do(func() {
+		markSafeDirectory(s.tdg.Root())
+	})
+}
+
+func isShallowRepository(root string) bool {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
+	cmd.Dir = root
+
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("Error while checking shallow repository. err=%v", err)
+		return false
+	}
+
+	return strings.TrimSpace(string(out)) == "true"
+}
+
+// shallowCommits returns boundary commits of the shallow repository
+func shallowCommits(root string) map[string]bool {
+	commits := make(map[string]bool)
+
+	cmd := exec.Command("git", "rev-parse", "--git-path", "shallow")
+	cmd.Dir = root
+
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("Error while locating shallow file. err=%v", err)
+		return commits
+	}
+
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+
+	lines, err := readLines(path)
+	if err != nil {
+		log.Printf("Error while reading shallow file. err=%v", err)
+		return commits
+	}
+
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			commits[l] = true
+		}
+	}
+
+	return commits
+}
+
+func deepenRepository(root, remote, sha string, depth int) error {
+	args := []string{"fetch", "--no-tags", "--depth=" + strconv.Itoa(depth), remote}
+	if len(sha) > 0 {
+		args = append(args, sha)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// isShallow checks once if the checkout is shallow and deepens it if configured.
+// In shallow repositories all lines older than the history are attributed
+// to the boundary commit so such blame details cannot be trusted
+func (s *service) isShallow() bool {
+	s.shallowOnce.Do(func() {
+		root := s.tdg.Root()
+		s.shallow = isShallowRepository(root)
+		if !s.shallow || s.env.blameFetchDepth <= 0 {
+			return
+		}
+
+		log.Printf("Deepening shallow repository. remote=%v depth=%v", s.env.blameRemote, s.env.blameFetchDepth)
+		if err := deepenRepository(root, s.env.blameRemote, s.env.sha, s.env.blameFetchDepth); err != nil {
+			log.Printf("Error while deepening repository. err=%v", err)
+		}
+
+		s.shallow = isShallowRepository(root)
+	})
+
+	return s.shallow
+}
+
+// trustBlame returns false for boundary commits of shallow repositories
+func (s *service) trustBlame(info *blameInfo) bool {
+	if !info.boundary || !s.isShallow() {
+		return true
+	}
+
+	s.shallowCommitsOnce.Do(func() {
+		s.shallowCommits = shallowCommits(s.tdg.Root())
+	})
+
+	return !s.shallowCommits[info.commitHash]
+}
+
+func (s *service) reportShallowBlame(untrusted int) {
+	if !s.isShallow() {
+		return
+	}
+
+	body := "Repository checkout is shallow so `git blame` attributes old lines to the oldest fetched commit. " +
+		"Use `fetch-depth: 0` in `actions/checkout` or `BLAME_FETCH_DEPTH` to get correct authors and ages."
+	if untrusted > 0 {
+		body += fmt.Sprintf("\n\n%v comments were attributed to the boundary commit and were not assigned or aged from blame.", untrusted)
+	}
+
+	s.report.addSection("Shallow repository", body)
+}
+
+// blameComments runs one git blame per file with comments in parallel and
+// fills commit details of the comments when they are used for assignment
+func (s *service) blameComments(comments []*tdglib.ToDoComment) {
+	s.markSafeDirectory()
+	s.isShallow()
+
+	files := make(map[string][]*tdglib.ToDoComment)
+	for _, c := range comments {
+		files[c.File] = append(files[c.File], c)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mux       sync.Mutex
+		untrusted int
+	)
+
+	semaphore := make(chan bool, s.env.concurrency)
+
+	for file, fileComments := range files {
+		wg.Add(1)
+		semaphore <- true
+
+		go func(file string, fileComments []*tdglib.ToDoComment) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			lines := make([]int, 0, len(fileComments))
+			for _, c := range fileComments {
+				lines = append(lines, c.Line)
+			}
+
+			blames, err := blameLines(s.tdg.Root(), file, lines)
+			if err != nil {
+				log.Printf("Error while running git blame. file=%v err=%v", file, err)
+				return
+			}
+
+			mux.Lock()
+			defer mux.Unlock()
+
+			for _, c := range fileComments {
+				info, ok := blames[c.Line]
+				if !ok {
+					log.Printf("No blame details for the comment. file=%v line=%v", file, c.Line)
+					continue
+				}
+
+				if !s.trustBlame(info) {
+					untrusted++
+					continue
+				}
+
+				s.blames[c] = info
+				if s.env.assignFromBlame || s.env.assignFromAuthor {
+					c.CommitHash = info.commitHash
+					c.CommitterEmail = info.committerEmail
+				}
+			}
+		}(file, fileComments)
+	}
+
+	wg.Wait()
+	log.Printf("Retrieved blame details. count=%v total=%v files=%v untrusted=%v", len(s.blames), len(comments), len(files), untrusted)
+	s.reportShallowBlame(untrusted)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const blamePorcelain = `3948976185f4a6e305d78677e47bdf1da867f08d 1 1 2
+author Jane Doe
+author-mail <jane@example.com>
+author-time 1700000000
+author-tz +0000
+committer John Doe
+committer-mail <john@example.com>
+committer-time 1700003600
+committer-tz +0000
+summary initial commit
+boundary
+filename main.go
+	package main
+3948976185f4a6e305d78677e47bdf1da867f08d 2 2
+	
+a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2 3 3 1
+author Jack Doe
+author-mail <jack@example.com>
+author-time 1710000000
+author-tz +0100
+committer Jack Doe
+committer-mail <jack@example.com>
+committer-time 1710000000
+committer-tz +0100
+summary add todo
+previous 3948976185f4a6e305d78677e47bdf1da867f08d main.go
+filename main.go
+	// TODO: something
+`
+
+func TestParseBlamePorcelain(t *testing.T) {
+	blames := parseBlamePorcelain([]byte(blamePorcelain))
+
+	if len(blames) != 3 {
+		t.Fatalf("parseBlamePorcelain() lines = %d, want 3", len(blames))
+	}
+
+	first := blames[1]
+	if first.commitHash != "3948976185f4a6e305d78677e47bdf1da867f08d" ||
+		first.authorEmail != "jane@example.com" ||
+		first.committerEmail != "john@example.com" ||
+		!first.boundary {
+		t.Fatalf("parseBlamePorcelain()[1] = %+v", first)
+	}
+
+	if !first.authorTime.Equal(time.Unix(1700000000, 0)) || !first.committerTime.Equal(time.Unix(1700003600, 0)) {
+		t.Fatalf("parseBlamePorcelain()[1] times = %v %v", first.authorTime, first.committerTime)
+	}
+
+	// repeated commit does not have the details in porcelain format
+	if blames[2] != first {
+		t.Fatalf("parseBlamePorcelain()[2] = %+v, want details of the first commit", blames[2])
+	}
+
+	third := blames[3]
+	if third.authorEmail != "jack@example.com" || third.boundary {
+		t.Fatalf("parseBlamePorcelain()[3] = %+v", third)
+	}
+}
+
+func TestBlameComments(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	// blameComments marks the root as safe in the global config
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	root := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane", "GIT_AUTHOR_EMAIL=jane@example.com", "GIT_AUTHOR_DATE=2020-01-01T00:00:00Z",
+			"GIT_COMMITTER_NAME=Jane", "GIT_COMMITTER_EMAIL=jane@example.com", "GIT_COMMITTER_DATE=2020-01-01T00:00:00Z")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v error = %v: %s", args, err, out)
+		}
+	}
+
+	source := "package main\n// TODO: first\n\n// TODO: second\n"
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte(source), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	git("init", "-q")
+	git("add", "main.go")
+	git("commit", "-q", "-m", "initial")
+
+	first := &tdglib.ToDoComment{File: "main.go", Line: 2}
+	second := &tdglib.ToDoComment{File: "main.go", Line: 4}
+
+	s := &service{
+		env:    &env{concurrency: 2, assignFromBlame: true},
+		tdg:    tdglib.NewToDoGenerator(root, nil, nil, false, 0, 0, 1),
+		blames: make(map[*tdglib.ToDoComment]*blameInfo),
+	}
+	s.blameComments([]*tdglib.ToDoComment{first, second})
+
+	for _, c := range []*tdglib.ToDoComment{first, second} {
+		info, ok := s.blames[c]
+		if !ok {
+			t.Fatalf("blameComments() has no details for line %v", c.Line)
+		}
+
+		if !info.authorTime.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Fatalf("blameComments() author time = %v", info.authorTime)
+		}
+
+		if c.CommitterEmail != "jane@example.com" || c.CommitHash != info.commitHash {
+			t.Fatalf("blameComments() comment = %+v", c)
+		}
+	}
+}
+
+func TestBlameCommentsInShallowRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	origin := t.TempDir()
+	git := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v error = %v: %s", args, err, out)
+		}
+	}
+
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(origin, "main.go"), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	git(origin, "init", "-q")
+	write("package main\n// TODO: old comment\n")
+	git(origin, "add", "main.go")
+	git(origin, "commit", "-q", "-m", "first")
+	write("package main\n// TODO: old comment\n\n")
+	git(origin, "commit", "-q", "-am", "second")
+	write("package main\n// TODO: old comment\n// TODO: new comment\n")
+	git(origin, "commit", "-q", "-am", "third")
+
+	for _, depth := range []int{0, 10} {
+		// second commit is the boundary of the clone
+		clone := t.TempDir()
+		git(clone, "clone", "-q", "--depth=2", "file://"+origin, ".")
+
+		old := &tdglib.ToDoComment{File: "main.go", Line: 2}
+		recent := &tdglib.ToDoComment{File: "main.go", Line: 3}
+
+		s := &service{
+			env:    &env{concurrency: 1, assignFromBlame: true, blameRemote: "origin", blameFetchDepth: depth},
+			tdg:    tdglib.NewToDoGenerator(clone, nil, nil, false, 0, 0, 1),
+			blames: make(map[*tdglib.ToDoComment]*blameInfo),
+			report: &report{},
+		}
+		s.blameComments([]*tdglib.ToDoComment{old, recent})
+
+		if _, ok := s.blames[recent]; !ok {
+			t.Fatalf("blameComments(depth=%v) has no details of the recent comment", depth)
+		}
+
+		_, ok := s.blames[old]
+		if depth == 0 && (ok || len(old.CommitHash) > 0 || !strings.Contains(s.report.String(), "shallow")) {
+			t.Fatalf("blameComments(depth=%v) trusted boundary commit in shallow repository", depth)
+		}
+
+		if depth > 0 && !ok {
+			t.Fatalf("blameComments(depth=%v) did not deepen the repository", depth)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	codeClimateIssueType       = "issue"
+	codeClimateDefaultSeverity = "info"
+	codeClimateDefaultCategory = "Clarity"
+)
+
+var codeClimateSeverities = map[string]string{
+	"BUG":   "major",
+	"FIXME": "minor",
+	"HACK":  "minor",
+	"TODO":  "info",
+}
+
+// categories allowed by the spec keyed by the lowercase name without
+// spaces, dashes or underscores so that "bug-risk" matches "Bug Risk"
+var codeClimateCategories = map[string]string{
+	"bugrisk":       "Bug Risk",
+	"clarity":       "Clarity",
+	"compatibility": "Compatibility",
+	"complexity":    "Complexity",
+	"duplication":   "Duplication",
+	"performance":   "Performance",
+	"security":      "Security",
+	"style":         "Style",
+}
+
+// https://github.com/codeclimate/platform/blob/master/spec/analyzers/SPEC.md#data-types
+type codeClimateLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end"`
+}
+
+type codeClimateLocation struct {
+	Path  string           `json:"path"`
+	Lines codeClimateLines `json:"lines"`
+}
+
+type codeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Severity    string              `json:"severity"`
+	Fingerprint string              `json:"fingerprint"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+func codeClimateSeverity(ctype string) string {
+	if severity, ok := codeClimateSeverities[strings.ToUpper(ctype)]; ok {
+		return severity
+	}
+
+	return codeClimateDefaultSeverity
+}
+
+func codeClimateCategory(category string) string {
+	key := strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(category))
+	if name, ok := codeClimateCategories[key]; ok {
+		return name
+	}
+
+	return codeClimateDefaultCategory
+}
+
+// fingerprint does not depend on the line so that moving the comment
+// around in the file does not produce a "new" issue in the report
+func codeClimateFingerprint(path string, c *tdglib.ToDoComment) string {
+	h := md5.New()
+	// separators keep "a/b"+"TODO" and "a/bT"+"ODO" apart
+	_, _ = io.WriteString(h, path)
+	_, _ = io.WriteString(h, "\x00")
+	_, _ = io.WriteString(h, strings.ToUpper(c.Type))
+	_, _ = io.WriteString(h, "\x00")
+	_, _ = io.WriteString(h, c.Title)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (e *env) codeClimateIssues(comments []*tdglib.ToDoComment) []*codeClimateIssue {
+	issues := make([]*codeClimateIssue, 0, len(comments))
+
+	for _, c := range comments {
+		path := strings.TrimPrefix(e.repoPath(c.File), "/")
+
+		issues = append(issues, &codeClimateIssue{
+			Type:        codeClimateIssueType,
+			CheckName:   strings.ToLower(c.Type),
+			Description: c.Title,
+			Categories:  []string{codeClimateCategory(c.Category)},
+			Severity:    codeClimateSeverity(c.Type),
+			Fingerprint: codeClimateFingerprint(path, c),
+			Location: codeClimateLocation{
+				Path:  path,
+				Lines: codeClimateLines{Begin: c.Line, End: c.Line},
+			},
+		})
+	}
+
+	return issues
+}
+
+func (e *env) writeCodeClimateReport(comments []*tdglib.ToDoComment) error {
+	data, err := json.MarshalIndent(e.codeClimateIssues(comments), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(e.codeQualityReport, data, 0644); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote code quality report. path=%v count=%v", e.codeQualityReport, len(comments))
+
+	return nil
+}
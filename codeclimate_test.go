@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestCodeClimateIssues(t *testing.T) {
+	e := &env{root: "src"}
+	comments := []*tdglib.ToDoComment{
+		{Type: "FIXME", Title: "Fix the parser", File: "parser/parser.go", Line: 42, Category: "Performance"},
+		{Type: "TODO", Title: "Add more tests", File: "main.go", Line: 7},
+		{Type: "TODO", Title: "Check input", File: "main.go", Line: 9, Category: "bug-risk"},
+		{Type: "TODO", Title: "Speed up", File: "main.go", Line: 11, Category: "api"},
+	}
+
+	issues := e.codeClimateIssues(comments)
+	if len(issues) != 4 {
+		t.Fatalf("codeClimateIssues() count = %d, want 4", len(issues))
+	}
+
+	first := issues[0]
+	if first.CheckName != "fixme" || first.Severity != "minor" {
+		t.Fatalf("codeClimateIssues() check = %q severity = %q, want fixme and minor", first.CheckName, first.Severity)
+	}
+
+	if first.Location.Path != "src/parser/parser.go" || first.Location.Lines.Begin != 42 {
+		t.Fatalf("codeClimateIssues() location = %+v, want src/parser/parser.go:42", first.Location)
+	}
+
+	if len(first.Categories) != 1 || first.Categories[0] != "Performance" {
+		t.Fatalf("codeClimateIssues() categories = %v, want [Performance]", first.Categories)
+	}
+
+	second := issues[1]
+	if second.Severity != "info" || second.Categories[0] != codeClimateDefaultCategory {
+		t.Fatalf("codeClimateIssues() severity = %q categories = %v", second.Severity, second.Categories)
+	}
+
+	if issues[2].Categories[0] != "Bug Risk" || issues[3].Categories[0] != codeClimateDefaultCategory {
+		t.Fatalf("codeClimateIssues() categories = %v, %v", issues[2].Categories, issues[3].Categories)
+	}
+}
+
+func TestCodeClimateFingerprintIgnoresLine(t *testing.T) {
+	c := &tdglib.ToDoComment{Type: "TODO", Title: "Refactor this", File: "a.go", Line: 1}
+	moved := &tdglib.ToDoComment{Type: "TODO", Title: "Refactor this", File: "a.go", Line: 100}
+
+	if codeClimateFingerprint("a.go", c) != codeClimateFingerprint("a.go", moved) {
+		t.Fatalf("codeClimateFingerprint() depends on the line number")
+	}
+
+	if codeClimateFingerprint("a.go", c) == codeClimateFingerprint("b.go", c) {
+		t.Fatalf("codeClimateFingerprint() does not depend on the path")
+	}
+
+	shifted := &tdglib.ToDoComment{Type: "ODO", Title: "Refactor this"}
+	if codeClimateFingerprint("a/b", c) == codeClimateFingerprint("a/bT", shifted) {
+		t.Fatalf("codeClimateFingerprint() fields are not separated")
+	}
+}
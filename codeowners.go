@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// locations are checked in the same order as GitHub does
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+type codeowners struct {
+	rules []*codeownersRule
+}
+
+// codeownersPattern converts gitignore-style pattern to a regular expression
+func codeownersPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	directory := strings.HasSuffix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("(^|/)")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	switch {
+	case directory:
+		b.WriteString("/")
+	case strings.HasSuffix(pattern, "/*"):
+		// unlike gitignore "docs/*" does not match nested directories
+		b.WriteString("$")
+	default:
+		// pattern matches the file itself or everything inside the directory
+		b.WriteString("(/|$)")
+	}
+
+	return regexp.Compile(b.String())
+}
+
+func parseCodeowners(content string) *codeowners {
+	co := &codeowners{}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		owners := make([]string, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "#") {
+				break
+			}
+			owners = append(owners, f)
+		}
+
+		pattern, err := codeownersPattern(fields[0])
+		if err != nil {
+			log.Printf("Error while parsing CODEOWNERS pattern. pattern=%v err=%v", fields[0], err)
+			continue
+		}
+
+		co.rules = append(co.rules, &codeownersRule{pattern: pattern, owners: owners})
+	}
+
+	return co
+}
+
+func loadCodeowners(repoRoot string) *codeowners {
+	for _, p := range codeownersPaths {
+		content, err := os.ReadFile(filepath.Join(repoRoot, p))
+		if err != nil {
+			continue
+		}
+
+		co := parseCodeowners(string(content))
+		log.Printf("Loaded CODEOWNERS. path=%v rules=%v", p, len(co.rules))
+
+		return co
+	}
+
+	return nil
+}
+
+// owners returns owners of the path relative to the repository root.
+// Last matching rule wins and it can have no owners at all
+func (co *codeowners) owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].pattern.MatchString(path) {
+			return co.rules[i].owners
+		}
+	}
+
+	return nil
+}
+
+// splitOwners separates users that can be assigned from teams and emails
+func splitOwners(owners []string) (users []string, mentions []string) {
+	for _, o := range owners {
+		switch {
+		case strings.HasPrefix(o, "@") && strings.Contains(o, "/"):
+			mentions = append(mentions, o)
+		case strings.HasPrefix(o, "@"):
+			users = append(users, strings.TrimPrefix(o, "@"))
+		default:
+			mentions = append(mentions, o)
+		}
+	}
+
+	return users, mentions
+}
+
+// codeownersBody mentions teams owning the path since they cannot be assigned
+func (s *service) codeownersBody(path string) string {
+	if s.codeowners == nil {
+		return ""
+	}
+
+	_, mentions := splitOwners(s.codeowners.owners(path))
+	if len(mentions) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Code owners: %s\n", strings.Join(mentions, " "))
+}
+
+// codeownersAssignee returns first owner of the path that can be assigned
+func (s *service) codeownersAssignee(path string) string {
+	if s.codeowners == nil {
+		return ""
+	}
+
+	users, _ := splitOwners(s.codeowners.owners(path))
+	for _, u := range users {
+		if s.assignable(u) {
+			return u
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCodeowners = `# default owners
+*       @global-owner
+
+*.js    @js-owner # inline comment
+/build/logs/ @doctocat
+docs/*  docs@example.com
+apps/   @octo-org/apps-team
+**/logs @logs-owner
+/scripts/ @scripts-owner
+/scripts/generated/
+`
+
+func TestCodeownersOwners(t *testing.T) {
+	co := parseCodeowners(testCodeowners)
+
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{path: "README.md", want: []string{"@global-owner"}},
+		{path: "src/app.js", want: []string{"@js-owner"}},
+		// later "**/logs" rule wins over "/build/logs/"
+		{path: "build/logs/out.txt", want: []string{"@logs-owner"}},
+		{path: "docs/index.md", want: []string{"docs@example.com"}},
+		{path: "docs/deep/index.md", want: []string{"@global-owner"}},
+		{path: "nested/apps/main.go", want: []string{"@octo-org/apps-team"}},
+		{path: "deploy/logs/out.txt", want: []string{"@logs-owner"}},
+		{path: "scripts/run.sh", want: []string{"@scripts-owner"}},
+		{path: "scripts/generated/run.sh", want: []string{}},
+	}
+
+	for _, tc := range cases {
+		got := co.owners(tc.path)
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Fatalf("owners(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestSplitOwners(t *testing.T) {
+	users, mentions := splitOwners([]string{"@octocat", "@octo-org/team", "mail@example.com"})
+
+	if strings.Join(users, " ") != "octocat" {
+		t.Fatalf("splitOwners() users = %v", users)
+	}
+
+	if strings.Join(mentions, " ") != "@octo-org/team mail@example.com" {
+		t.Fatalf("splitOwners() mentions = %v", mentions)
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	discussionMarker      = "<!-- tdg-discussion -->"
+	discussionMetadataKey = "discussion"
+	discussionTypesNone   = "none"
+)
+
+var errDiscussionCategoryNotFound = errors.New("discussion category not found")
+
+const discussionCategoriesQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    id
+    discussionCategories(first: 100) {
+      nodes { id name isAnswerable }
+    }
+  }
+}`
+
+const discussionsQuery = `query($owner: String!, $name: String!, $category: ID!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    discussions(first: 100, after: $cursor, categoryId: $category) {
+      nodes { id title body locked closed }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+const createDiscussionMutation = `mutation($repository: ID!, $category: ID!, $title: String!, $body: String!) {
+  createDiscussion(input: {repositoryId: $repository, categoryId: $category, title: $title, body: $body}) {
+    discussion { id }
+  }
+}`
+
+const addDiscussionCommentMutation = `mutation($discussion: ID!, $body: String!) {
+  addDiscussionComment(input: {discussionId: $discussion, body: $body}) {
+    comment { id }
+  }
+}`
+
+const markDiscussionAnswerMutation = `mutation($comment: ID!) {
+  markDiscussionCommentAsAnswer(input: {id: $comment}) {
+    discussion { id }
+  }
+}`
+
+const closeDiscussionMutation = `mutation($discussion: ID!) {
+  closeDiscussion(input: {discussionId: $discussion, reason: RESOLVED}) {
+    discussion { id }
+  }
+}`
+
+const lockDiscussionMutation = `mutation($lockable: ID!) {
+  lockLockable(input: {lockableId: $lockable}) {
+    lockedRecord { locked }
+  }
+}`
+
+const unlockDiscussionMutation = `mutation($lockable: ID!) {
+  unlockLockable(input: {lockableId: $lockable}) {
+    unlockedRecord { locked }
+  }
+}`
+
+const reopenDiscussionMutation = `mutation($discussion: ID!) {
+  reopenDiscussion(input: {discussionId: $discussion}) {
+    discussion { id }
+  }
+}`
+
+type discussion struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	Locked bool   `json:"locked"`
+	Closed bool   `json:"closed"`
+}
+
+type discussionCategory struct {
+	repositoryID string
+	id           string
+	answerable   bool
+}
+
+// isDiscussion checks if the comment goes to discussions instead of
+// issues, "discussion=" key of the comment wins over the type
+func (s *service) isDiscussion(c *tdglib.ToDoComment) bool {
+	if len(s.env.discussionCategory) == 0 {
+		return false
+	}
+
+	if value, ok := s.metadata[c][discussionMetadataKey]; ok {
+		return flagToBool(value)
+	}
+
+	if len(s.env.discussionTypes) == 0 {
+		return true
+	}
+
+	for _, t := range strings.Split(s.env.discussionTypes, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), c.Type) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitDiscussionComments separates comments that go to discussions
+func (s *service) splitDiscussionComments(comments []*tdglib.ToDoComment) ([]*tdglib.ToDoComment, []*tdglib.ToDoComment) {
+	issues := make([]*tdglib.ToDoComment, 0, len(comments))
+	discussions := make([]*tdglib.ToDoComment, 0)
+
+	for _, c := range comments {
+		if s.isDiscussion(c) {
+			discussions = append(discussions, c)
+		} else {
+			issues = append(issues, c)
+		}
+	}
+
+	return issues, discussions
+}
+
+// unknownDiscussionTypes returns types that never match since tdg
+// only reports comments of badgeTypes
+func (e *env) unknownDiscussionTypes() []string {
+	unknown := make([]string, 0)
+
+	for _, t := range strings.Split(e.discussionTypes, ",") {
+		t = strings.TrimSpace(t)
+		if len(t) == 0 || strings.EqualFold(t, discussionTypesNone) || containsString(badgeTypes, strings.ToUpper(t)) {
+			continue
+		}
+
+		unknown = append(unknown, t)
+	}
+
+	return unknown
+}
+
+// reportUnknownDiscussionTypes points to the "discussion=" key instead
+func (s *service) reportUnknownDiscussionTypes() {
+	unknown := s.env.unknownDiscussionTypes()
+	if len(unknown) == 0 {
+		return
+	}
+
+	log.Printf("Ignoring unknown discussion types. types=%v", unknown)
+
+	lines := make([]string, 0, len(unknown))
+	for _, t := range unknown {
+		lines = append(lines, fmt.Sprintf("- `%s` is not a comment type tdg recognizes (%s)", markdownEscape(t), strings.Join(badgeTypes, ", ")))
+	}
+	lines = append(lines, "", "Mark such comments with `discussion=1` instead.")
+
+	s.report.addSection("Unknown discussion types", strings.Join(lines, "\n"))
+}
+
+func (s *service) loadDiscussionCategory() (*discussionCategory, error) {
+	var result struct {
+		Repository *struct {
+			ID                   string `json:"id"`
+			DiscussionCategories struct {
+				Nodes []struct {
+					ID           string `json:"id"`
+					Name         string `json:"name"`
+					IsAnswerable bool   `json:"isAnswerable"`
+				} `json:"nodes"`
+			} `json:"discussionCategories"`
+		} `json:"repository"`
+	}
+
+	variables := map[string]interface{}{"owner": s.env.issueOwner, "name": s.env.issueRepo}
+	if err := s.client.graphQL(s.ctx, "discussion_categories", discussionCategoriesQuery, variables, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Repository == nil {
+		return nil, errDiscussionCategoryNotFound
+	}
+
+	for _, c := range result.Repository.DiscussionCategories.Nodes {
+		if strings.EqualFold(c.Name, s.env.discussionCategory) {
+			return &discussionCategory{repositoryID: result.Repository.ID, id: c.ID, answerable: c.IsAnswerable}, nil
+		}
+	}
+
+	return nil, errDiscussionCategoryNotFound
+}
+
+// fetchDiscussions returns discussions of the category created by the action
+func (s *service) fetchDiscussions(category *discussionCategory) ([]*discussion, error) {
+	var all []*discussion
+
+	variables := map[string]interface{}{"owner": s.env.issueOwner, "name": s.env.issueRepo, "category": category.id}
+	for {
+		var result struct {
+			Repository struct {
+				Discussions struct {
+					Nodes    []*discussion `json:"nodes"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"discussions"`
+			} `json:"repository"`
+		}
+
+		if err := s.client.graphQL(s.ctx, "discussions", discussionsQuery, variables, &result); err != nil {
+			return nil, err
+		}
+
+		for _, d := range result.Repository.Discussions.Nodes {
+			if strings.Contains(d.Body, discussionMarker) {
+				all = append(all, d)
+			}
+		}
+
+		if !result.Repository.Discussions.PageInfo.HasNextPage {
+			break
+		}
+
+		variables["cursor"] = result.Repository.Discussions.PageInfo.EndCursor
+	}
+
+	log.Printf("Fetched todo discussions. count=%v", len(all))
+
+	return all, nil
+}
+
+func (s *service) discussionBody(c *tdglib.ToDoComment) string {
+	body := c.Body + "\n\n"
+	if len(c.Author) > 0 {
+		body += fmt.Sprintf("Author: @%s\n", c.Author)
+	}
+
+	return body + fmt.Sprintf("Line: %v\n%s\n\n%s", c.Line, s.createFileLink(c), discussionMarker)
+}
+
+// resolveDiscussion credits the removing commit and marks the comment as the
+// answer when the category supports it, otherwise closes the discussion
+func (s *service) resolveDiscussion(category *discussionCategory, d *discussion) error {
+	// body has the file link to search the removing commit in
+	comment, _ := s.closingComment(&github.Issue{Title: &d.Title, Body: &d.Body})
+
+	var added struct {
+		AddDiscussionComment struct {
+			Comment struct {
+				ID string `json:"id"`
+			} `json:"comment"`
+		} `json:"addDiscussionComment"`
+	}
+
+	variables := map[string]interface{}{"discussion": d.ID, "body": comment}
+	if err := s.client.graphQL(s.ctx, "add_discussion_comment", addDiscussionCommentMutation, variables, &added); err != nil {
+		return err
+	}
+
+	if category.answerable {
+		variables = map[string]interface{}{"comment": added.AddDiscussionComment.Comment.ID}
+		if err := s.client.graphQL(s.ctx, "mark_discussion_answer", markDiscussionAnswerMutation, variables, nil); err != nil {
+			return err
+		}
+	} else if !d.Closed {
+		variables = map[string]interface{}{"discussion": d.ID}
+		if err := s.client.graphQL(s.ctx, "close_discussion", closeDiscussionMutation, variables, nil); err != nil {
+			return err
+		}
+	}
+
+	variables = map[string]interface{}{"lockable": d.ID}
+
+	return s.client.graphQL(s.ctx, "lock_discussion", lockDiscussionMutation, variables, nil)
+}
+
+// restoreDiscussion unlocks and reopens the discussion of a re-added comment
+func (s *service) restoreDiscussion(d *discussion) error {
+	variables := map[string]interface{}{"lockable": d.ID}
+	if err := s.client.graphQL(s.ctx, "unlock_discussion", unlockDiscussionMutation, variables, nil); err != nil {
+		return err
+	}
+
+	if !d.Closed {
+		return nil
+	}
+
+	variables = map[string]interface{}{"discussion": d.ID}
+
+	return s.client.graphQL(s.ctx, "reopen_discussion", reopenDiscussionMutation, variables, nil)
+}
+
+// syncDiscussions creates discussions for new comments and resolves
+// discussions of removed comments matching them by title like issues
+func (s *service) syncDiscussions(category *discussionCategory, comments []*tdglib.ToDoComment) {
+	discussions, err := s.fetchDiscussions(category)
+	if err != nil {
+		log.Printf("Error while fetching discussions. err=%v", err)
+		return
+	}
+
+	existing := make(map[string]*discussion, len(discussions))
+	for _, d := range discussions {
+		existing[d.Title] = d
+	}
+
+	titles := make(map[string]bool, len(comments))
+	created, restored := 0, 0
+
+	for _, c := range comments {
+		titles[c.Title] = true
+		if s.snoozed[c] {
+			continue
+		}
+
+		if d, ok := existing[c.Title]; ok {
+			if !d.Locked {
+				continue
+			}
+
+			log.Printf("About to restore a discussion. title=%v", c.Title)
+
+			if s.env.dryRun {
+				log.Printf("Dry run mode.")
+				continue
+			}
+
+			if err := s.restoreDiscussion(d); err != nil {
+				log.Printf("Error while restoring a discussion. title=%v err=%v", c.Title, err)
+				continue
+			}
+
+			restored++
+			continue
+		}
+
+		if s.env.addLimit > 0 && s.createdCount+created >= s.env.addLimit {
+			log.Printf("Exceeded limit of discussions to create. limit=%v", s.env.addLimit)
+			break
+		}
+
+		log.Printf("About to create a discussion. title=%v", c.Title)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		variables := map[string]interface{}{
+			"repository": category.repositoryID,
+			"category":   category.id,
+			"title":      c.Title,
+			"body":       s.discussionBody(c),
+		}
+		if err := s.client.graphQL(s.ctx, "create_discussion", createDiscussionMutation, variables, nil); err != nil {
+			log.Printf("Error while creating a discussion. title=%v err=%v", c.Title, err)
+			continue
+		}
+
+		created++
+	}
+
+	resolved := 0
+	for _, d := range discussions {
+		if titles[d.Title] || d.Locked {
+			continue
+		}
+
+		log.Printf("About to resolve a discussion. title=%v", d.Title)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		if err := s.resolveDiscussion(category, d); err != nil {
+			log.Printf("Error while resolving a discussion. title=%v err=%v", d.Title, err)
+			continue
+		}
+
+		resolved++
+	}
+
+	s.createdCount += created
+	s.closedCount += resolved
+	log.Printf("Synced discussions. created=%v resolved=%v restored=%v", created, resolved, restored)
+}
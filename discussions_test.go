@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestSplitDiscussionComments(t *testing.T) {
+	marked := &tdglib.ToDoComment{Type: "TODO"}
+	excluded := &tdglib.ToDoComment{Type: "HACK"}
+	comments := []*tdglib.ToDoComment{{Type: "TODO"}, {Type: "HACK"}, {Type: "hack"}, marked, excluded}
+
+	s := &service{
+		env: &env{discussionCategory: "Ideas", discussionTypes: "hack, question"},
+		metadata: map[*tdglib.ToDoComment]map[string]string{
+			marked:   {discussionMetadataKey: "1"},
+			excluded: {discussionMetadataKey: "0"},
+		},
+	}
+
+	issues, discussions := s.splitDiscussionComments(comments)
+	if len(issues) != 2 || len(discussions) != 3 {
+		t.Fatalf("splitDiscussionComments() = %v, %v", len(issues), len(discussions))
+	}
+
+	s.env = &env{discussionCategory: "Ideas", discussionTypes: "none"}
+	if issues, discussions = s.splitDiscussionComments(comments); len(issues) != 4 || len(discussions) != 1 {
+		t.Fatalf("splitDiscussionComments() for marked comments = %v, %v", len(issues), len(discussions))
+	}
+
+	s.env = &env{discussionCategory: "Ideas"}
+	if issues, discussions = s.splitDiscussionComments(comments); len(issues) != 1 || len(discussions) != 4 {
+		t.Fatalf("splitDiscussionComments() for all types = %v, %v", len(issues), len(discussions))
+	}
+
+	s.env = &env{discussionTypes: "hack"}
+	if issues, _ = s.splitDiscussionComments(comments); len(issues) != 5 {
+		t.Fatalf("splitDiscussionComments() without category = %v", len(issues))
+	}
+}
+
+func TestReportUnknownDiscussionTypes(t *testing.T) {
+	s := &service{
+		env:    &env{discussionCategory: "Ideas", discussionTypes: "HACK, question,none,"},
+		report: &report{},
+	}
+
+	if unknown := s.env.unknownDiscussionTypes(); len(unknown) != 1 || unknown[0] != "question" {
+		t.Fatalf("unknownDiscussionTypes() = %v", unknown)
+	}
+
+	s.reportUnknownDiscussionTypes()
+	if summary := s.report.String(); !strings.Contains(summary, "`question` is not a comment type") {
+		t.Fatalf("reportUnknownDiscussionTypes() report = %v", summary)
+	}
+}
+
+func TestLoadDiscussionCategoryMissing(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"repository":{"id":"R","discussionCategories":{"nodes":[{"id":"C","name":"Ideas"}]}}}}`))
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{issueOwner: "o", issueRepo: "r", discussionCategory: "Idaes"},
+	}
+
+	if _, err := s.loadDiscussionCategory(); !errors.Is(err, errDiscussionCategoryNotFound) {
+		t.Fatalf("loadDiscussionCategory() error = %v, want %v", err, errDiscussionCategoryNotFound)
+	}
+}
+
+func TestSyncDiscussions(t *testing.T) {
+	operations := make([]string, 0)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/graphql":
+		case "/repos/o/r/commits/sha/pulls":
+			_, _ = w.Write([]byte(`[]`))
+			return
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+			return
+		}
+
+		var req graphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Decode() error = %v", err)
+			return
+		}
+
+		switch req.Query {
+		case discussionCategoriesQuery:
+			_, _ = w.Write([]byte(`{"data":{"repository":{"id":"R","discussionCategories":{"nodes":[{"id":"C","name":"Q&A","isAnswerable":true}]}}}}`))
+		case discussionsQuery:
+			_, _ = w.Write([]byte(`{"data":{"repository":{"discussions":{"nodes":[
+				{"id":"D1","title":"kept","body":"<!-- tdg-discussion -->"},
+				{"id":"D2","title":"removed","body":"<!-- tdg-discussion -->"},
+				{"id":"D3","title":"locked","body":"<!-- tdg-discussion -->","locked":true},
+				{"id":"D4","title":"manual","body":"written by hand"},
+				{"id":"D5","title":"closed","body":"<!-- tdg-discussion -->","locked":true,"closed":true}
+			],"pageInfo":{"hasNextPage":false}}}}}`))
+		case createDiscussionMutation:
+			operations = append(operations, "create "+req.Variables["title"].(string))
+			if !strings.Contains(req.Variables["body"].(string), discussionMarker) {
+				t.Errorf("createDiscussion body = %v", req.Variables["body"])
+			}
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case addDiscussionCommentMutation:
+			operations = append(operations, "comment "+req.Variables["discussion"].(string))
+			_, _ = w.Write([]byte(`{"data":{"addDiscussionComment":{"comment":{"id":"DC"}}}}`))
+		case markDiscussionAnswerMutation:
+			operations = append(operations, "answer "+req.Variables["comment"].(string))
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case lockDiscussionMutation:
+			operations = append(operations, "lock "+req.Variables["lockable"].(string))
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case unlockDiscussionMutation:
+			operations = append(operations, "unlock "+req.Variables["lockable"].(string))
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		case reopenDiscussionMutation:
+			operations = append(operations, "reopen "+req.Variables["discussion"].(string))
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		default:
+			t.Errorf("unexpected query %v", req.Query)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "r", issueOwner: "o", issueRepo: "r", sha: "sha", discussionCategory: "q&a"},
+		tdg:    tdglib.NewToDoGenerator(t.TempDir(), nil, nil, false, 0, 0, 1),
+	}
+
+	category, err := s.loadDiscussionCategory()
+	if err != nil {
+		t.Fatalf("loadDiscussionCategory() error = %v", err)
+	}
+
+	s.syncDiscussions(category, []*tdglib.ToDoComment{
+		{Title: "kept", Type: "HACK"},
+		{Title: "new", Type: "HACK", File: "main.go", Line: 1},
+		{Title: "locked", Type: "HACK"},
+		{Title: "closed", Type: "HACK"},
+	})
+
+	want := []string{"create new", "unlock D3", "unlock D5", "reopen D5", "comment D2", "answer DC", "lock D2"}
+	if strings.Join(operations, ",") != strings.Join(want, ",") {
+		t.Fatalf("syncDiscussions() operations = %v, want %v", operations, want)
+	}
+
+	// removing commit is searched in the file linked from the discussion
+	body := s.discussionBody(&tdglib.ToDoComment{Title: "new", File: "main.go", Line: 1})
+	if path := s.issueFilePath(&github.Issue{Body: &body}); path != "main.go" {
+		t.Fatalf("issueFilePath() of discussion = %q, want main.go", path)
+	}
+
+	if s.createdCount != 1 || s.closedCount != 1 {
+		t.Fatalf("syncDiscussions() created = %v closed = %v", s.createdCount, s.closedCount)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	dueMetadataKey      = "due"
+	dueDateLayout       = "2006-01-02"
+	dueMilestonePrefix  = "Due "
+	defaultOverdueLabel = "overdue"
+)
+
+var errCannotParseDue = errors.New("cannot parse due date")
+
+// parseDue parses either an absolute date or a relative duration
+// like "3d", "2w", "1m" or "1y" counted from the anchor
+func parseDue(value string, anchor time.Time) (time.Time, error) {
+	if date, err := time.Parse(dueDateLayout, value); err == nil {
+		return date, nil
+	}
+
+	if len(value) < 2 {
+		return time.Time{}, errCannotParseDue
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, errCannotParseDue
+	}
+
+	if anchor.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: relative date without blame", errCannotParseDue)
+	}
+
+	anchor = anchor.UTC().Truncate(day)
+	switch value[len(value)-1] {
+	case 'd':
+		return anchor.AddDate(0, 0, n), nil
+	case 'w':
+		return anchor.AddDate(0, 0, 7*n), nil
+	case 'm':
+		return anchor.AddDate(0, n, 0), nil
+	case 'y':
+		return anchor.AddDate(n, 0, 0), nil
+	}
+
+	return time.Time{}, errCannotParseDue
+}
+
+// commentBlame returns blame details of the comment running git blame if needed
+func (s *service) commentBlame(c *tdglib.ToDoComment) *blameInfo {
+	if info, ok := s.blames[c]; ok {
+		return info
+	}
+
+	info, err := blameLine(s.tdg.Root(), c.File, c.Line)
+	if err != nil {
+		log.Printf("Error while running git blame. file=%v line=%v err=%v", c.File, c.Line, err)
+		return nil
+	}
+
+	if !s.trustBlame(info) {
+		log.Printf("Ignoring blame of the boundary commit in shallow repository. file=%v line=%v", c.File, c.Line)
+		return nil
+	}
+
+	s.blames[c] = info
+
+	return info
+}
+
+// metadataDate parses date from the metadata key of the comment, relative
+// dates are counted from the date the comment was written
+func (s *service) metadataDate(c *tdglib.ToDoComment, key string) (time.Time, bool, error) {
+	value, ok := s.metadata[c][key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	var anchor time.Time
+	if _, err := time.Parse(dueDateLayout, value); err != nil {
+		if info := s.commentBlame(c); info != nil {
+			anchor = info.authorTime
+		}
+	}
+
+	date, err := parseDue(value, anchor)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%v=%v: %w", key, value, err)
+	}
+
+	return date, true, nil
+}
+
+// resolveDueDates has to be called before issues are processed in parallel
+func (s *service) resolveDueDates(comments []*tdglib.ToDoComment) {
+	for _, c := range comments {
+		due, ok, err := s.metadataDate(c, dueMetadataKey)
+		if err != nil {
+			log.Printf("Error while parsing due date. file=%v line=%v err=%v", c.File, c.Line, err)
+			continue
+		}
+
+		if ok {
+			s.dueDates[c] = due
+		}
+	}
+
+	log.Printf("Resolved due dates. count=%v", len(s.dueDates))
+}
+
+// milestone finds or creates a milestone by title
+func (s *service) milestone(title string, dueOn *time.Time) (int, error) {
+	if s.milestones == nil {
+		s.milestones = make(map[string]*github.Milestone)
+
+		opt := &github.MilestoneListOptions{
+			State:       "all",
+			ListOptions: github.ListOptions{PerPage: defaultIssuesPerPage},
+		}
+
+		for {
+			milestones, resp, err := s.client.listMilestones(s.ctx, s.env.issueOwner, s.env.issueRepo, opt)
+			if err != nil {
+				s.milestones = nil
+				return 0, err
+			}
+
+			for _, m := range milestones {
+				s.milestones[m.GetTitle()] = m
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+
+			opt.ListOptions.Page = resp.NextPage
+		}
+	}
+
+	if m, ok := s.milestones[title]; ok {
+		return m.GetNumber(), nil
+	}
+
+	req := &github.Milestone{Title: &title}
+	if dueOn != nil {
+		req.DueOn = &github.Timestamp{Time: *dueOn}
+	}
+
+	m, _, err := s.client.createMilestone(s.ctx, s.env.issueOwner, s.env.issueRepo, req)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Created a milestone. title=%v", title)
+	s.milestones[title] = m
+
+	return m.GetNumber(), nil
+}
+
+// dueMilestone finds or creates a milestone for the due date
+func (s *service) dueMilestone(due time.Time) (int, error) {
+	return s.milestone(dueMilestonePrefix+due.Format(dueDateLayout), &due)
+}
+
+// markOverdueIssues labels and comments on issues once their due date passes
+func (s *service) markOverdueIssues(issueMap map[string]*github.Issue, comments []*tdglib.ToDoComment) {
+	count := 0
+
+	for _, c := range comments {
+		due, ok := s.dueDates[c]
+		if !ok || s.now.Before(due.Add(day)) {
+			continue
+		}
+
+		i, ok := issueMap[c.Title]
+		if !ok || i.GetState() == "closed" {
+			continue
+		}
+
+		labels := labelNames(i)
+		if containsString(labels, s.env.overdueLabel) {
+			continue
+		}
+
+		log.Printf("About to mark an issue as overdue. issue=%v due=%v", i.GetNumber(), due.Format(dueDateLayout))
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		labels = append(labels, s.env.overdueLabel)
+		req := &github.IssueRequest{Labels: &labels}
+		edited, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), req)
+		if err != nil {
+			log.Printf("Error while marking an issue as overdue. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		i.Labels = edited.Labels
+		if s.env.commentIssue {
+			s.commentIssue(fmt.Sprintf("This TODO was due on %v.", due.Format(dueDateLayout)), i)
+		}
+		count++
+	}
+
+	log.Printf("Marked overdue issues. count=%v", count)
+}
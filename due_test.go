@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestParseDue(t *testing.T) {
+	anchor := time.Date(2024, 1, 31, 15, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		value string
+		want  time.Time
+	}{
+		{value: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "3d", want: time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{value: "2w", want: time.Date(2024, 2, 14, 0, 0, 0, 0, time.UTC)},
+		{value: "1y", want: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		got, err := parseDue(tc.value, anchor)
+		if err != nil {
+			t.Fatalf("parseDue(%q) error = %v", tc.value, err)
+		}
+
+		if !got.Equal(tc.want) {
+			t.Fatalf("parseDue(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+
+	for _, value := range []string{"", "soon", "2x", "2024-13-01"} {
+		if _, err := parseDue(value, anchor); !errors.Is(err, errCannotParseDue) {
+			t.Fatalf("parseDue(%q) error = %v, want %v", value, err, errCannotParseDue)
+		}
+	}
+
+	if _, err := parseDue("2w", time.Time{}); !errors.Is(err, errCannotParseDue) {
+		t.Fatalf("parseDue() without anchor error = %v, want %v", err, errCannotParseDue)
+	}
+}
+
+func TestDueMilestoneAndOverdue(t *testing.T) {
+	edits := make([]*github.IssueRequest, 0)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/milestones":
+			_, _ = w.Write([]byte(`[{"number":3,"title":"Due 2024-01-31"}]`))
+		case "PATCH /repos/o/r/issues/1":
+			var req github.IssueRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			edits = append(edits, &req)
+			_, _ = w.Write([]byte(`{"number":1}`))
+		default:
+			// comments are disabled
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	c := &tdglib.ToDoComment{Title: "due", File: "a.go", Line: 1}
+
+	s := &service{
+		ctx:      context.Background(),
+		client:   newTestGitHubAPI(t, handler),
+		env:      &env{issueOwner: "o", issueRepo: "r", dueMilestones: true, overdueLabel: "overdue"},
+		metadata: map[*tdglib.ToDoComment]map[string]string{c: {"due": "2024-01-31"}},
+		dueDates: map[*tdglib.ToDoComment]time.Time{c: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		now:      time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	issueMap := map[string]*github.Issue{"due": {Number: github.Ptr(1)}}
+	comments := []*tdglib.ToDoComment{c}
+
+	s.updateIssuesMetadata(issueMap, comments)
+	s.markOverdueIssues(issueMap, comments)
+
+	if len(edits) != 2 || edits[0].GetMilestone() != 3 || edits[1].Labels == nil || (*edits[1].Labels)[0] != "overdue" {
+		t.Fatalf("edits = %+v", edits)
+	}
+}
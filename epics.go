@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	epicsByCategory  = "category"
+	epicsByDir       = "dir"
+	epicTitlePrefix  = "Epic: "
+	epicMarkerFormat = "<!-- tdg-epic: %s -->"
+	epicMarkerPrefix = "<!-- tdg-epic: "
+)
+
+var epicChildRE = regexp.MustCompile(`(?m)^- \[[ x]\] #(\d+)`)
+
+func isEpicsMode(mode string) bool {
+	return mode == epicsByCategory || mode == epicsByDir
+}
+
+// splitEpicIssues separates epics so that they are never treated
+// as issues of the removed comments
+func splitEpicIssues(issues []*github.Issue) ([]*github.Issue, []*github.Issue) {
+	todos := make([]*github.Issue, 0, len(issues))
+	epics := make([]*github.Issue, 0)
+
+	for _, i := range issues {
+		if strings.Contains(i.GetBody(), epicMarkerPrefix) {
+			epics = append(epics, i)
+		} else {
+			todos = append(todos, i)
+		}
+	}
+
+	return todos, epics
+}
+
+// epicKey returns category or top level directory of the comment
+func (e *env) epicKey(c *tdglib.ToDoComment) string {
+	if e.epics == epicsByCategory {
+		return c.Category
+	}
+
+	dir, _, ok := strings.Cut(strings.TrimPrefix(e.repoPath(c.File), "/"), "/")
+	if !ok {
+		return ""
+	}
+
+	return dir
+}
+
+func parseEpicChildren(body string) []int {
+	children := make([]int, 0)
+	for _, m := range epicChildRE.FindAllStringSubmatch(body, -1) {
+		if number, err := strconv.Atoi(m[1]); err == nil {
+			children = append(children, number)
+		}
+	}
+
+	return children
+}
+
+type epicChild struct {
+	issue    *github.Issue
+	estimate float64
+	parent   int
+}
+
+// epicBody renders roll-up of the children and the task list
+func (s *service) epicBody(key string, children []*epicChild) (string, int) {
+	open, closed := 0, 0
+	estimate := 0.0
+
+	var tasks strings.Builder
+	for _, child := range children {
+		prefix := taskOpenPrefix
+		if child.issue.GetState() == "closed" {
+			prefix = taskDonePrefix
+			closed++
+		} else {
+			open++
+			estimate += child.estimate
+		}
+
+		fmt.Fprintf(&tasks, "%s#%v %s\n", prefix, child.issue.GetNumber(), markdownEscape(child.issue.GetTitle()))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, epicMarkerFormat+"\n", s.env.epics)
+	fmt.Fprintf(&b, "TODO comments in `%s`.\n\n", key)
+	fmt.Fprintf(&b, "Open: %v, closed: %v", open, closed)
+	if estimate > 0 {
+		fmt.Fprintf(&b, ", estimate: %v", formatEstimate(estimate))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(tasks.String())
+
+	return b.String(), open
+}
+
+// updateEpics maintains an epic issue per category or directory with all
+// issues of its comments. Children that were closed stay in the list
+func (s *service) updateEpics(epics []*github.Issue, issues []*github.Issue, comments []*tdglib.ToDoComment) {
+	marker := fmt.Sprintf(epicMarkerFormat, s.env.epics)
+
+	existing := make(map[string]*github.Issue)
+	for _, i := range epics {
+		if strings.Contains(i.GetBody(), marker) && strings.HasPrefix(i.GetTitle(), epicTitlePrefix) {
+			existing[strings.TrimPrefix(i.GetTitle(), epicTitlePrefix)] = i
+		}
+	}
+
+	byTitle := make(map[string]*github.Issue)
+	byNumber := make(map[int]*github.Issue)
+	for _, i := range issues {
+		byTitle[i.GetTitle()] = i
+		byNumber[i.GetNumber()] = i
+	}
+	for title, i := range s.newIssuesMap {
+		byTitle[title] = i
+		byNumber[i.GetNumber()] = i
+	}
+
+	groups := make(map[string][]*epicChild)
+	listed := make(map[int]bool)
+
+	for _, c := range comments {
+		key := s.env.epicKey(c)
+		i, ok := byTitle[c.Title]
+		if len(key) == 0 || !ok || listed[i.GetNumber()] {
+			continue
+		}
+
+		listed[i.GetNumber()] = true
+		groups[key] = append(groups[key], &epicChild{issue: i, estimate: c.Estimate, parent: c.Issue})
+	}
+
+	// removed comments are not parsed anymore so their issues are
+	// found via the previous task list
+	for key, epic := range existing {
+		for _, number := range parseEpicChildren(epic.GetBody()) {
+			if i, ok := byNumber[number]; ok && !listed[number] {
+				listed[number] = true
+				groups[key] = append(groups[key], &epicChild{issue: i})
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	count := 0
+	for _, key := range keys {
+		children := groups[key]
+		sort.Slice(children, func(a, b int) bool {
+			return children[a].issue.GetNumber() < children[b].issue.GetNumber()
+		})
+
+		epic := existing[key]
+		body, open := s.epicBody(key, children)
+
+		state := "open"
+		if open == 0 {
+			state = "closed"
+		}
+
+		if epic != nil && body == epic.GetBody() && state == epic.GetState() {
+			continue
+		}
+
+		log.Printf("About to update an epic. key=%v children=%v open=%v", key, len(children), open)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		previous := make(map[int]bool)
+		if epic == nil {
+			if open == 0 {
+				continue
+			}
+
+			title := epicTitlePrefix + key
+			labels := []string{s.env.label}
+			created, _, err := s.client.createIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, &github.IssueRequest{Title: &title, Body: &body, Labels: &labels})
+			if err != nil {
+				log.Printf("Error while creating an epic. key=%v err=%v", key, err)
+				continue
+			}
+			epic = created
+		} else {
+			for _, number := range parseEpicChildren(epic.GetBody()) {
+				previous[number] = true
+			}
+
+			req := &github.IssueRequest{Body: &body, State: &state}
+			if _, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, epic.GetNumber(), req); err != nil {
+				log.Printf("Error while updating an epic. issue=%v err=%v", epic.GetNumber(), err)
+				continue
+			}
+		}
+
+		count++
+
+		if !s.env.subIssues {
+			continue
+		}
+
+		// issues with "issue=" parent are already sub-issues of that parent
+		for _, child := range children {
+			if previous[child.issue.GetNumber()] || child.parent > 0 || child.issue.GetState() == "closed" {
+				continue
+			}
+
+			if err := s.attachSubIssue(epic.GetNumber(), child.issue); err != nil {
+				log.Printf("Error while attaching issue to the epic. epic=%v issue=%v err=%v", epic.GetNumber(), child.issue.GetNumber(), err)
+			}
+		}
+	}
+
+	log.Printf("Updated epics. count=%v", count)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestEpicKey(t *testing.T) {
+	c := &tdglib.ToDoComment{Category: "Parser", File: "pkg/parser/lexer.go"}
+
+	if got := (&env{epics: epicsByCategory}).epicKey(c); got != "Parser" {
+		t.Fatalf("epicKey(category) = %v", got)
+	}
+
+	if got := (&env{epics: epicsByDir}).epicKey(c); got != "pkg" {
+		t.Fatalf("epicKey(dir) = %v", got)
+	}
+
+	if got := (&env{epics: epicsByDir}).epicKey(&tdglib.ToDoComment{File: "main.go"}); got != "" {
+		t.Fatalf("epicKey(dir) for root file = %v", got)
+	}
+}
+
+func TestSplitEpicIssues(t *testing.T) {
+	issues := []*github.Issue{
+		{Number: github.Ptr(1), Body: github.Ptr("todo")},
+		{Number: github.Ptr(2), Body: github.Ptr("<!-- tdg-epic: category -->\n")},
+	}
+
+	todos, epics := splitEpicIssues(issues)
+	if len(todos) != 1 || todos[0].GetNumber() != 1 || len(epics) != 1 || epics[0].GetNumber() != 2 {
+		t.Fatalf("splitEpicIssues() = %v, %v", todos, epics)
+	}
+}
+
+func TestUpdateEpics(t *testing.T) {
+	bodies := make(map[string]*github.IssueRequest)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req github.IssueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Decode() error = %v", err)
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /repos/o/r/issues":
+			bodies[req.GetTitle()] = &req
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":100}`))
+		case "PATCH /repos/o/r/issues/50":
+			bodies["Epic: Parser"] = &req
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:          context.Background(),
+		client:       newTestGitHubAPI(t, handler),
+		env:          &env{issueOwner: "o", issueRepo: "r", label: "todo", epics: epicsByCategory},
+		newIssuesMap: map[string]*github.Issue{"new": {Number: github.Ptr(3), Title: github.Ptr("new"), State: github.Ptr("open")}},
+	}
+
+	epics := []*github.Issue{{
+		Number: github.Ptr(50),
+		Title:  github.Ptr("Epic: Parser"),
+		State:  github.Ptr("open"),
+		Body:   github.Ptr("<!-- tdg-epic: category -->\n- [ ] #1 open\n- [ ] #2 removed\n"),
+	}}
+	issues := []*github.Issue{
+		{Number: github.Ptr(1), Title: github.Ptr("open"), State: github.Ptr("open")},
+		{Number: github.Ptr(2), Title: github.Ptr("removed"), State: github.Ptr("closed")},
+	}
+	comments := []*tdglib.ToDoComment{
+		{Title: "open", Category: "Parser", Estimate: 1},
+		{Title: "new", Category: "Lexer", Estimate: 0.5},
+		{Title: "uncategorized"},
+	}
+
+	s.updateEpics(epics, issues, comments)
+
+	parser := bodies["Epic: Parser"]
+	if parser == nil || parser.GetState() != "open" ||
+		!strings.Contains(parser.GetBody(), "Open: 1, closed: 1, estimate: 1h\n") ||
+		!strings.Contains(parser.GetBody(), "- [ ] #1 open\n- [x] #2 removed\n") {
+		t.Fatalf("updateEpics() parser = %+v", parser)
+	}
+
+	lexer := bodies["Epic: Lexer"]
+	if lexer == nil || !strings.Contains(lexer.GetBody(), "Open: 1, closed: 0, estimate: 30m\n\n- [ ] #3 new\n") {
+		t.Fatalf("updateEpics() lexer = %+v", lexer)
+	}
+}
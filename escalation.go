@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	defaultStaleLabel    = "stale"
+	defaultEscalateLabel = "escalated"
+)
+
+func labelNames(i *github.Issue) []string {
+	names := make([]string, 0, len(i.Labels))
+	for _, l := range i.Labels {
+		names = append(names, l.GetName())
+	}
+
+	return names
+}
+
+// issueAge prefers the age of the comment itself and falls back to the age of the issue
+func (s *service) issueAge(i *github.Issue, c *tdglib.ToDoComment) time.Duration {
+	if age, ok := s.age(c); ok {
+		return age
+	}
+
+	return s.now.Sub(i.GetCreatedAt().Time)
+}
+
+func (s *service) escalationMentions(i *github.Issue, c *tdglib.ToDoComment) string {
+	mentions := make([]string, 0, len(i.Assignees))
+	for _, a := range i.Assignees {
+		mentions = append(mentions, "@"+a.GetLogin())
+	}
+
+	if len(mentions) == 0 && len(c.Author) > 0 {
+		mentions = append(mentions, "@"+c.Author)
+	}
+
+	if len(mentions) == 0 && len(i.GetUser().GetLogin()) > 0 {
+		mentions = append(mentions, "@"+i.GetUser().GetLogin())
+	}
+
+	return strings.Join(mentions, " ")
+}
+
+// escalateStaleIssues marks old issues as stale and pings the people responsible
+// for them once, using the escalation label to remember who was pinged already
+func (s *service) escalateStaleIssues(issueMap map[string]*github.Issue, comments []*tdglib.ToDoComment) {
+	staleAge := time.Duration(s.env.staleDays) * day
+	escalateAge := time.Duration(s.env.escalateDays) * day
+	count := 0
+
+	for _, c := range comments {
+		i, ok := issueMap[c.Title]
+		if !ok || i.GetState() == "closed" {
+			continue
+		}
+
+		age := s.issueAge(i, c)
+		labels := labelNames(i)
+		changed := false
+
+		if s.env.staleDays > 0 && age >= staleAge && !containsString(labels, s.env.staleLabel) {
+			labels = append(labels, s.env.staleLabel)
+			changed = true
+		}
+
+		escalate := s.env.escalateDays > 0 && age >= escalateAge && !containsString(labels, s.env.escalateLabel)
+		if escalate {
+			labels = append(labels, s.env.escalateLabel)
+			changed = true
+		}
+
+		if !changed {
+			continue
+		}
+
+		log.Printf("About to escalate an issue. issue=%v age=%v escalate=%v", i.GetNumber(), formatAge(age), escalate)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		req := &github.IssueRequest{Labels: &labels}
+		edited, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), req)
+		if err != nil {
+			log.Printf("Error while escalating an issue. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		i.Labels = edited.Labels
+
+		// comment only after the label is set so that nobody is pinged twice
+		if escalate {
+			body := fmt.Sprintf("This TODO is %v old. %v could you please take a look?", formatAge(age), s.escalationMentions(i, c))
+			s.commentIssue(strings.TrimSpace(body), i)
+		}
+
+		count++
+	}
+
+	log.Printf("Escalated stale issues. count=%v", count)
+}
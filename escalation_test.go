@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestEscalateStaleIssues(t *testing.T) {
+	var (
+		mux      sync.Mutex
+		edits    = make(map[string][]string)
+		comments []string
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		switch {
+		case r.Method == http.MethodPatch:
+			var req github.IssueRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			edits[r.URL.Path] = *req.Labels
+
+			labels := make([]*github.Label, 0, len(*req.Labels))
+			for _, l := range *req.Labels {
+				labels = append(labels, &github.Label{Name: github.Ptr(l)})
+			}
+			_ = json.NewEncoder(w).Encode(&github.Issue{Labels: labels})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comments"):
+			var comment github.IssueComment
+			_ = json.NewDecoder(r.Body).Decode(&comment)
+			comments = append(comments, comment.GetBody())
+			_ = json.NewEncoder(w).Encode(&comment)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := func(days int) *github.Timestamp {
+		return &github.Timestamp{Time: now.Add(-time.Duration(days) * day)}
+	}
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		now:    now,
+		blames: make(map[*tdglib.ToDoComment]*blameInfo),
+		env: &env{
+			issueOwner:    "o",
+			issueRepo:     "r",
+			staleDays:     30,
+			staleLabel:    defaultStaleLabel,
+			escalateDays:  90,
+			escalateLabel: defaultEscalateLabel,
+		},
+	}
+
+	issueMap := map[string]*github.Issue{
+		"fresh":     {Number: github.Ptr(1), CreatedAt: created(1)},
+		"stale":     {Number: github.Ptr(2), CreatedAt: created(40)},
+		"escalate":  {Number: github.Ptr(3), CreatedAt: created(100), Assignees: []*github.User{{Login: github.Ptr("octocat")}}},
+		"escalated": {Number: github.Ptr(4), CreatedAt: created(100), Labels: []*github.Label{{Name: github.Ptr("stale")}, {Name: github.Ptr("escalated")}}},
+	}
+
+	todos := []*tdglib.ToDoComment{{Title: "fresh"}, {Title: "stale"}, {Title: "escalate"}, {Title: "escalated"}}
+	s.escalateStaleIssues(issueMap, todos)
+
+	if len(edits) != 2 {
+		t.Fatalf("escalateStaleIssues() edits = %v, want 2 issues", edits)
+	}
+
+	if got := edits["/repos/o/r/issues/2"]; len(got) != 1 || got[0] != "stale" {
+		t.Fatalf("escalateStaleIssues() labels of stale issue = %v", got)
+	}
+
+	if got := edits["/repos/o/r/issues/3"]; len(got) != 2 || got[1] != "escalated" {
+		t.Fatalf("escalateStaleIssues() labels of escalated issue = %v", got)
+	}
+
+	if len(comments) != 1 || !strings.Contains(comments[0], "@octocat") {
+		t.Fatalf("escalateStaleIssues() comments = %v", comments)
+	}
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v73/github"
@@ -19,6 +22,8 @@ const (
 	defaultGitHubAPIRetryFactor = 2
 )
 
+var errGraphQL = errors.New("graphql error")
+
 type githubAPI struct {
 	client     *github.Client
 	times      int
@@ -149,6 +154,480 @@ func (g *githubAPI) doCreateComment(ctx context.Context, owner, repo string, num
 	return g.client.Issues.CreateComment(ctx, owner, repo, number, comment)
 }
 
+func (g *githubAPI) getRepository(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error) {
+	var (
+		repository *github.Repository
+		resp       *github.Response
+	)
+
+	err := g.retry(ctx, "repositories.get", func() error {
+		var err error
+		repository, resp, err = g.doGetRepository(ctx, owner, repo)
+		return err
+	})
+
+	return repository, resp, err
+}
+
+func (g *githubAPI) doGetRepository(ctx context.Context, owner, repo string) (*github.Repository, *github.Response, error) {
+	return g.client.Repositories.Get(ctx, owner, repo)
+}
+
+func (g *githubAPI) getRef(ctx context.Context, owner, repo, ref string) (*github.Reference, *github.Response, error) {
+	var (
+		reference *github.Reference
+		resp      *github.Response
+	)
+
+	err := g.retry(ctx, "git.get_ref", func() error {
+		var err error
+		reference, resp, err = g.doGetRef(ctx, owner, repo, ref)
+		return err
+	})
+
+	return reference, resp, err
+}
+
+func (g *githubAPI) doGetRef(ctx context.Context, owner, repo, ref string) (*github.Reference, *github.Response, error) {
+	return g.client.Git.GetRef(ctx, owner, repo, ref)
+}
+
+func (g *githubAPI) compareCommits(ctx context.Context, owner, repo, base, head string) (*github.CommitsComparison, *github.Response, error) {
+	var (
+		comparison *github.CommitsComparison
+		resp       *github.Response
+	)
+
+	err := g.retry(ctx, "repositories.compare_commits", func() error {
+		var err error
+		comparison, resp, err = g.doCompareCommits(ctx, owner, repo, base, head)
+		return err
+	})
+
+	return comparison, resp, err
+}
+
+func (g *githubAPI) doCompareCommits(ctx context.Context, owner, repo, base, head string) (*github.CommitsComparison, *github.Response, error) {
+	// only the status is needed, so commits are not paginated
+	return g.client.Repositories.CompareCommits(ctx, owner, repo, base, head, &github.ListOptions{PerPage: 1})
+}
+
+func (g *githubAPI) createRef(ctx context.Context, owner, repo string, ref *github.Reference) (*github.Reference, *github.Response, error) {
+	var (
+		created *github.Reference
+		resp    *github.Response
+	)
+
+	err := g.retry(ctx, "git.create_ref", func() error {
+		var err error
+		created, resp, err = g.doCreateRef(ctx, owner, repo, ref)
+		return err
+	})
+
+	return created, resp, err
+}
+
+func (g *githubAPI) doCreateRef(ctx context.Context, owner, repo string, ref *github.Reference) (*github.Reference, *github.Response, error) {
+	return g.client.Git.CreateRef(ctx, owner, repo, ref)
+}
+
+func (g *githubAPI) getBlobRaw(ctx context.Context, owner, repo, sha string) ([]byte, *github.Response, error) {
+	var (
+		blob []byte
+		resp *github.Response
+	)
+
+	err := g.retry(ctx, "git.get_blob", func() error {
+		var err error
+		blob, resp, err = g.doGetBlobRaw(ctx, owner, repo, sha)
+		return err
+	})
+
+	return blob, resp, err
+}
+
+func (g *githubAPI) doGetBlobRaw(ctx context.Context, owner, repo, sha string) ([]byte, *github.Response, error) {
+	return g.client.Git.GetBlobRaw(ctx, owner, repo, sha)
+}
+
+func (g *githubAPI) createTree(ctx context.Context, owner, repo string, entries []*github.TreeEntry) (*github.Tree, *github.Response, error) {
+	var (
+		tree *github.Tree
+		resp *github.Response
+	)
+
+	err := g.retry(ctx, "git.create_tree", func() error {
+		var err error
+		tree, resp, err = g.doCreateTree(ctx, owner, repo, entries)
+		return err
+	})
+
+	return tree, resp, err
+}
+
+func (g *githubAPI) doCreateTree(ctx context.Context, owner, repo string, entries []*github.TreeEntry) (*github.Tree, *github.Response, error) {
+	return g.client.Git.CreateTree(ctx, owner, repo, "", entries)
+}
+
+func (g *githubAPI) createCommit(ctx context.Context, owner, repo string, commit *github.Commit) (*github.Commit, *github.Response, error) {
+	var (
+		created *github.Commit
+		resp    *github.Response
+	)
+
+	err := g.retry(ctx, "git.create_commit", func() error {
+		var err error
+		created, resp, err = g.doCreateCommit(ctx, owner, repo, commit)
+		return err
+	})
+
+	return created, resp, err
+}
+
+func (g *githubAPI) doCreateCommit(ctx context.Context, owner, repo string, commit *github.Commit) (*github.Commit, *github.Response, error) {
+	return g.client.Git.CreateCommit(ctx, owner, repo, commit, nil)
+}
+
+func (g *githubAPI) getFile(ctx context.Context, owner, repo, path string, opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, *github.Response, error) {
+	var (
+		file *github.RepositoryContent
+		resp *github.Response
+	)
+
+	err := g.retry(ctx, "repositories.get_contents", func() error {
+		var err error
+		file, resp, err = g.doGetFile(ctx, owner, repo, path, opt)
+		return err
+	})
+
+	return file, resp, err
+}
+
+func (g *githubAPI) doGetFile(ctx context.Context, owner, repo, path string, opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, *github.Response, error) {
+	file, _, resp, err := g.client.Repositories.GetContents(ctx, owner, repo, path, opt)
+	return file, resp, err
+}
+
+// updateFile creates the file when opt.SHA is not set
+func (g *githubAPI) updateFile(ctx context.Context, owner, repo, path string, opt *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error) {
+	var (
+		updated *github.RepositoryContentResponse
+		resp    *github.Response
+	)
+
+	err := g.retry(ctx, "repositories.update_file", func() error {
+		var err error
+		updated, resp, err = g.doUpdateFile(ctx, owner, repo, path, opt)
+		return err
+	})
+
+	return updated, resp, err
+}
+
+func (g *githubAPI) doUpdateFile(ctx context.Context, owner, repo, path string, opt *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error) {
+	return g.client.Repositories.UpdateFile(ctx, owner, repo, path, opt)
+}
+
+func (g *githubAPI) listMilestones(ctx context.Context, owner, repo string, opt *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error) {
+	var (
+		milestones []*github.Milestone
+		resp       *github.Response
+	)
+
+	err := g.retry(ctx, "issues.list_milestones", func() error {
+		var err error
+		milestones, resp, err = g.doListMilestones(ctx, owner, repo, opt)
+		return err
+	})
+
+	return milestones, resp, err
+}
+
+func (g *githubAPI) doListMilestones(ctx context.Context, owner, repo string, opt *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error) {
+	return g.client.Issues.ListMilestones(ctx, owner, repo, opt)
+}
+
+func (g *githubAPI) createMilestone(ctx context.Context, owner, repo string, milestone *github.Milestone) (*github.Milestone, *github.Response, error) {
+	var (
+		created *github.Milestone
+		resp    *github.Response
+	)
+
+	err := g.retry(ctx, "issues.create_milestone", func() error {
+		var err error
+		created, resp, err = g.doCreateMilestone(ctx, owner, repo, milestone)
+		return err
+	})
+
+	return created, resp, err
+}
+
+func (g *githubAPI) doCreateMilestone(ctx context.Context, owner, repo string, milestone *github.Milestone) (*github.Milestone, *github.Response, error) {
+	return g.client.Issues.CreateMilestone(ctx, owner, repo, milestone)
+}
+
+func (g *githubAPI) isAssignee(ctx context.Context, owner, repo, user string) (bool, *github.Response, error) {
+	var (
+		assignable bool
+		resp       *github.Response
+	)
+
+	err := g.retry(ctx, "issues.is_assignee", func() error {
+		var err error
+		assignable, resp, err = g.doIsAssignee(ctx, owner, repo, user)
+		return err
+	})
+
+	return assignable, resp, err
+}
+
+func (g *githubAPI) doIsAssignee(ctx context.Context, owner, repo, user string) (bool, *github.Response, error) {
+	return g.client.Issues.IsAssignee(ctx, owner, repo, user)
+}
+
+func (g *githubAPI) searchUsers(ctx context.Context, query string, opt *github.SearchOptions) (*github.UsersSearchResult, *github.Response, error) {
+	var (
+		result *github.UsersSearchResult
+		resp   *github.Response
+	)
+
+	err := g.retry(ctx, "search.users", func() error {
+		var err error
+		result, resp, err = g.doSearchUsers(ctx, query, opt)
+		return err
+	})
+
+	return result, resp, err
+}
+
+func (g *githubAPI) doSearchUsers(ctx context.Context, query string, opt *github.SearchOptions) (*github.UsersSearchResult, *github.Response, error) {
+	return g.client.Search.Users(ctx, query, opt)
+}
+
+func (g *githubAPI) listPullRequestsWithCommit(ctx context.Context, owner, repo, sha string, opt *github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+	var (
+		pulls []*github.PullRequest
+		resp  *github.Response
+	)
+
+	err := g.retry(ctx, "pulls.list_with_commit", func() error {
+		var err error
+		pulls, resp, err = g.doListPullRequestsWithCommit(ctx, owner, repo, sha, opt)
+		return err
+	})
+
+	return pulls, resp, err
+}
+
+func (g *githubAPI) doListPullRequestsWithCommit(ctx context.Context, owner, repo, sha string, opt *github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+	return g.client.PullRequests.ListPullRequestsWithCommit(ctx, owner, repo, sha, opt)
+}
+
+func (g *githubAPI) getIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, *github.Response, error) {
+	var (
+		issue *github.Issue
+		resp  *github.Response
+	)
+
+	err := g.retry(ctx, "issues.get", func() error {
+		var err error
+		issue, resp, err = g.doGetIssue(ctx, owner, repo, number)
+		return err
+	})
+
+	return issue, resp, err
+}
+
+func (g *githubAPI) doGetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, *github.Response, error) {
+	return g.client.Issues.Get(ctx, owner, repo, number)
+}
+
+func (g *githubAPI) addSubIssue(ctx context.Context, owner, repo string, number int, subIssue github.SubIssueRequest) (*github.SubIssue, *github.Response, error) {
+	var (
+		added *github.SubIssue
+		resp  *github.Response
+	)
+
+	err := g.retry(ctx, "sub_issues.add", func() error {
+		var err error
+		added, resp, err = g.doAddSubIssue(ctx, owner, repo, number, subIssue)
+		return err
+	})
+
+	return added, resp, err
+}
+
+func (g *githubAPI) doAddSubIssue(ctx context.Context, owner, repo string, number int, subIssue github.SubIssueRequest) (*github.SubIssue, *github.Response, error) {
+	return g.client.SubIssue.Add(ctx, owner, repo, int64(number), subIssue)
+}
+
+func (g *githubAPI) removeSubIssue(ctx context.Context, owner, repo string, number int, subIssue github.SubIssueRequest) (*github.Response, error) {
+	var resp *github.Response
+
+	err := g.retry(ctx, "sub_issues.remove", func() error {
+		var err error
+		resp, err = g.doRemoveSubIssue(ctx, owner, repo, number, subIssue)
+		return err
+	})
+
+	return resp, err
+}
+
+// doRemoveSubIssue does not use SubIssue.Remove since it calls
+// "sub_issues" instead of the documented "sub_issue" endpoint
+func (g *githubAPI) doRemoveSubIssue(ctx context.Context, owner, repo string, number int, subIssue github.SubIssueRequest) (*github.Response, error) {
+	u := fmt.Sprintf("repos/%v/%v/issues/%v/sub_issue", owner, repo, number)
+	req, err := g.client.NewRequest(http.MethodDelete, u, subIssue)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.client.Do(ctx, req, nil)
+}
+
+func (g *githubAPI) listIssueTypes(ctx context.Context, org string) ([]*github.IssueType, *github.Response, error) {
+	var (
+		types []*github.IssueType
+		resp  *github.Response
+	)
+
+	err := g.retry(ctx, "orgs.list_issue_types", func() error {
+		var err error
+		types, resp, err = g.doListIssueTypes(ctx, org)
+		return err
+	})
+
+	return types, resp, err
+}
+
+func (g *githubAPI) doListIssueTypes(ctx context.Context, org string) ([]*github.IssueType, *github.Response, error) {
+	return g.client.Organizations.ListIssueTypes(ctx, org)
+}
+
+func (g *githubAPI) listLabels(ctx context.Context, owner, repo string, opt *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	var (
+		labels []*github.Label
+		resp   *github.Response
+	)
+
+	err := g.retry(ctx, "issues.list_labels", func() error {
+		var err error
+		labels, resp, err = g.doListLabels(ctx, owner, repo, opt)
+		return err
+	})
+
+	return labels, resp, err
+}
+
+func (g *githubAPI) doListLabels(ctx context.Context, owner, repo string, opt *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	return g.client.Issues.ListLabels(ctx, owner, repo, opt)
+}
+
+func (g *githubAPI) createLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error) {
+	var (
+		created *github.Label
+		resp    *github.Response
+	)
+
+	err := g.retry(ctx, "issues.create_label", func() error {
+		var err error
+		created, resp, err = g.doCreateLabel(ctx, owner, repo, label)
+		return err
+	})
+
+	return created, resp, err
+}
+
+func (g *githubAPI) doCreateLabel(ctx context.Context, owner, repo string, label *github.Label) (*github.Label, *github.Response, error) {
+	return g.client.Issues.CreateLabel(ctx, owner, repo, label)
+}
+
+func (g *githubAPI) editLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error) {
+	var (
+		edited *github.Label
+		resp   *github.Response
+	)
+
+	err := g.retry(ctx, "issues.edit_label", func() error {
+		var err error
+		edited, resp, err = g.doEditLabel(ctx, owner, repo, name, label)
+		return err
+	})
+
+	return edited, resp, err
+}
+
+func (g *githubAPI) doEditLabel(ctx context.Context, owner, repo, name string, label *github.Label) (*github.Label, *github.Response, error) {
+	return g.client.Issues.EditLabel(ctx, owner, repo, name, label)
+}
+
+func (g *githubAPI) deleteLabel(ctx context.Context, owner, repo, name string) (*github.Response, error) {
+	var resp *github.Response
+
+	err := g.retry(ctx, "issues.delete_label", func() error {
+		var err error
+		resp, err = g.doDeleteLabel(ctx, owner, repo, name)
+		return err
+	})
+
+	return resp, err
+}
+
+func (g *githubAPI) doDeleteLabel(ctx context.Context, owner, repo, name string) (*github.Response, error) {
+	return g.client.Issues.DeleteLabel(ctx, owner, repo, name)
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphQL runs the query and decodes its data into the result
+func (g *githubAPI) graphQL(ctx context.Context, operation, query string, variables map[string]interface{}, result interface{}) error {
+	return g.retry(ctx, "graphql."+operation, func() error {
+		return g.doGraphQL(ctx, query, variables, result)
+	})
+}
+
+func (g *githubAPI) doGraphQL(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	// GitHub Enterprise serves GraphQL from /api/graphql next to /api/v3/
+	endpoint := "graphql"
+	if strings.HasSuffix(g.client.BaseURL.Path, "/api/v3/") {
+		endpoint = "../graphql"
+	}
+
+	req, err := g.client.NewRequest(http.MethodPost, endpoint, &graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+
+	response := &graphQLResponse{}
+	if _, err = g.client.Do(ctx, req, response); err != nil {
+		return err
+	}
+
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("%w: %s", errGraphQL, strings.Join(messages, "; "))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(response.Data, result)
+}
+
 func (g *githubAPI) retry(ctx context.Context, operation string, fn func() error) error {
 	b := g.newBackoff()
 	var err error
@@ -205,3 +684,10 @@ func isRetryableGitHubError(err error) bool {
 	var netErr net.Error
 	return errors.As(err, &netErr) && netErr.Timeout()
 }
+
+func isNotFoundGitHubError(err error) bool {
+	var responseErr *github.ErrorResponse
+	return errors.As(err, &responseErr) &&
+		responseErr.Response != nil &&
+		responseErr.Response.StatusCode == http.StatusNotFound
+}
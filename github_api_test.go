@@ -4,6 +4,7 @@ import (
 	"context"
 	errors "errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
@@ -190,3 +191,23 @@ type timeoutError struct{}
 func (timeoutError) Error() string   { return "timeout" }
 func (timeoutError) Timeout() bool   { return true }
 func (timeoutError) Temporary() bool { return true }
+
+// newTestGitHubAPI returns API client that sends all requests to the handler
+func newTestGitHubAPI(t *testing.T, handler http.Handler) *githubAPI {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	client.BaseURL = baseURL
+
+	api := newGitHubAPI(client)
+	api.times = 1
+
+	return api
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	defaultHistoryFile = "tdg-history.json"
+	// encoding of files over 1 MB in the contents API
+	historyLargeFileEncoding = "none"
+	chartWidth               = 800
+	chartHeight              = 300
+	chartPadding             = 40
+)
+
+var chartColors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b"}
+
+type historySnapshot struct {
+	Timestamp time.Time      `json:"timestamp"`
+	SHA       string         `json:"sha"`
+	Total     int            `json:"total"`
+	Types     map[string]int `json:"types"`
+	Areas     map[string]int `json:"areas,omitempty"`
+	Estimate  float64        `json:"estimate"`
+	Created   int            `json:"created"`
+	Closed    int            `json:"closed"`
+}
+
+func newHistorySnapshot(now time.Time, sha string, comments []*tdglib.ToDoComment, created, closed int) *historySnapshot {
+	snapshot := &historySnapshot{
+		Timestamp: now.UTC(),
+		SHA:       sha,
+		Total:     len(comments),
+		Types:     make(map[string]int),
+		Areas:     make(map[string]int),
+		Created:   created,
+		Closed:    closed,
+	}
+
+	for _, c := range comments {
+		snapshot.Types[strings.ToUpper(c.Type)]++
+		if len(c.Category) > 0 {
+			snapshot.Areas[c.Category]++
+		}
+		snapshot.Estimate += c.Estimate
+	}
+
+	return snapshot
+}
+
+// renderHistoryChart draws total amount of comments and amount per type over time
+func renderHistoryChart(history []*historySnapshot) []byte {
+	maxValue := 1
+	typesMap := make(map[string]bool)
+	for _, h := range history {
+		if h.Total > maxValue {
+			maxValue = h.Total
+		}
+		for t := range h.Types {
+			typesMap[t] = true
+		}
+	}
+
+	types := make([]string, 0, len(typesMap))
+	for t := range typesMap {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	point := func(i, value int) string {
+		x := float64(chartPadding)
+		if len(history) > 1 {
+			x += plotWidth * float64(i) / float64(len(history)-1)
+		}
+		y := float64(chartHeight-chartPadding) - plotHeight*float64(value)/float64(maxValue)
+		return fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999999"/>`+"\n",
+		chartPadding, chartHeight-chartPadding, chartWidth-chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999999"/>`+"\n",
+		chartPadding, chartPadding, chartPadding, chartHeight-chartPadding)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%d</text>`+"\n", chartPadding-4, chartPadding+4, maxValue)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">0</text>`+"\n", chartPadding-4, chartHeight-chartPadding+4)
+
+	if len(history) > 0 {
+		first, last := history[0], history[len(history)-1]
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n",
+			chartPadding, chartHeight-chartPadding/2, first.Timestamp.Format("2006-01-02"))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n",
+			chartWidth-chartPadding, chartHeight-chartPadding/2, last.Timestamp.Format("2006-01-02"))
+	}
+
+	series := append([]string{"total"}, types...)
+	for si, name := range series {
+		points := make([]string, 0, len(history))
+		for i, h := range history {
+			value := h.Total
+			if si > 0 {
+				value = h.Types[name]
+			}
+			points = append(points, point(i, value))
+		}
+
+		color := chartColors[si%len(chartColors)]
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, strings.Join(points, " "))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n", chartPadding+si*90, chartPadding/2, color, name)
+	}
+
+	b.WriteString("</svg>\n")
+
+	return b.Bytes()
+}
+
+// ensureHistoryBranch creates the history branch as an orphan commit with
+// an empty history file so that it does not carry the source tree
+func (s *service) ensureHistoryBranch() error {
+	_, _, err := s.client.getRef(s.ctx, s.env.codeOwner, s.env.codeRepo, "heads/"+s.env.historyBranch)
+	if err == nil {
+		return nil
+	}
+
+	if !isNotFoundGitHubError(err) {
+		return err
+	}
+
+	// trees cannot be empty
+	entries := []*github.TreeEntry{{
+		Path:    github.Ptr(s.env.historyFile),
+		Mode:    github.Ptr("100644"),
+		Type:    github.Ptr("blob"),
+		Content: github.Ptr("[]\n"),
+	}}
+	tree, _, err := s.client.createTree(s.ctx, s.env.codeOwner, s.env.codeRepo, entries)
+	if err != nil {
+		return err
+	}
+
+	commit := &github.Commit{
+		Message: github.Ptr("Create TODO history"),
+		Tree:    &github.Tree{SHA: tree.SHA},
+	}
+	created, _, err := s.client.createCommit(s.ctx, s.env.codeOwner, s.env.codeRepo, commit)
+	if err != nil {
+		return err
+	}
+
+	ref := &github.Reference{
+		Ref:    github.Ptr("refs/heads/" + s.env.historyBranch),
+		Object: &github.GitObject{SHA: created.SHA},
+	}
+	if _, _, err := s.client.createRef(s.ctx, s.env.codeOwner, s.env.codeRepo, ref); err != nil {
+		return err
+	}
+
+	log.Printf("Created history branch. branch=%v", s.env.historyBranch)
+
+	return nil
+}
+
+func (s *service) historyFile(path string) ([]byte, *string, error) {
+	opt := &github.RepositoryContentGetOptions{Ref: s.env.historyBranch}
+	file, _, err := s.client.getFile(s.ctx, s.env.codeOwner, s.env.codeRepo, path, opt)
+	if err != nil {
+		if isNotFoundGitHubError(err) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	// contents API does not return files over 1 MB, the history grows
+	// with every run so it is read as a blob then
+	if file.GetEncoding() == historyLargeFileEncoding {
+		blob, _, err := s.client.getBlobRaw(s.ctx, s.env.codeOwner, s.env.codeRepo, file.GetSHA())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return blob, file.SHA, nil
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []byte(content), file.SHA, nil
+}
+
+func (s *service) commitHistoryFile(path string, content []byte, sha *string) error {
+	opt := &github.RepositoryContentFileOptions{
+		Message: github.Ptr(fmt.Sprintf("Update %v for %v", path, s.env.sha)),
+		Content: content,
+		SHA:     sha,
+		Branch:  &s.env.historyBranch,
+	}
+
+	_, _, err := s.client.updateFile(s.ctx, s.env.codeOwner, s.env.codeRepo, path, opt)
+
+	return err
+}
+
+func (s *service) recordHistory(comments []*tdglib.ToDoComment) error {
+	repo, _, err := s.client.getRepository(s.ctx, s.env.codeOwner, s.env.codeRepo)
+	if err != nil {
+		return err
+	}
+
+	if defaultBranch := repo.GetDefaultBranch(); defaultBranch != s.env.branch {
+		log.Printf("Skipping history for non-default branch. branch=%v default=%v", s.env.branch, defaultBranch)
+		return nil
+	}
+
+	snapshot := newHistorySnapshot(s.now, s.env.sha, comments, s.createdCount, s.closedCount)
+
+	if s.env.dryRun {
+		log.Printf("Dry run mode. Skipping history snapshot. total=%v", snapshot.Total)
+		return nil
+	}
+
+	if err := s.ensureHistoryBranch(); err != nil {
+		return err
+	}
+
+	data, sha, err := s.historyFile(s.env.historyFile)
+	if err != nil {
+		return err
+	}
+
+	var history []*historySnapshot
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &history); err != nil {
+			return err
+		}
+	}
+	history = append(history, snapshot)
+
+	data, err = json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := s.commitHistoryFile(s.env.historyFile, data, sha); err != nil {
+		return err
+	}
+
+	log.Printf("Appended history snapshot. file=%v snapshots=%v", s.env.historyFile, len(history))
+
+	if len(s.env.historyChart) == 0 {
+		return nil
+	}
+
+	_, chartSHA, err := s.historyFile(s.env.historyChart)
+	if err != nil {
+		return err
+	}
+
+	if err := s.commitHistoryFile(s.env.historyChart, renderHistoryChart(history), chartSHA); err != nil {
+		return err
+	}
+
+	log.Printf("Updated history chart. file=%v", s.env.historyChart)
+
+	return nil
+}
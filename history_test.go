@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestNewHistorySnapshot(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	comments := []*tdglib.ToDoComment{
+		{Type: "TODO", Title: "a", Category: "api", Estimate: 1.5},
+		{Type: "todo", Title: "b", Estimate: 0.5},
+		{Type: "FIXME", Title: "c", Category: "api"},
+	}
+
+	snapshot := newHistorySnapshot(now, "abc", comments, 2, 1)
+
+	if snapshot.Total != 3 || snapshot.Created != 2 || snapshot.Closed != 1 {
+		t.Fatalf("newHistorySnapshot() = %+v", snapshot)
+	}
+
+	if snapshot.Types["TODO"] != 2 || snapshot.Types["FIXME"] != 1 {
+		t.Fatalf("newHistorySnapshot() types = %v", snapshot.Types)
+	}
+
+	if snapshot.Areas["api"] != 2 || len(snapshot.Areas) != 1 {
+		t.Fatalf("newHistorySnapshot() areas = %v", snapshot.Areas)
+	}
+
+	if snapshot.Estimate != 2 {
+		t.Fatalf("newHistorySnapshot() estimate = %v, want 2", snapshot.Estimate)
+	}
+}
+
+func TestRenderHistoryChart(t *testing.T) {
+	history := []*historySnapshot{
+		{Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Total: 10, Types: map[string]int{"TODO": 10}},
+		{Timestamp: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Total: 4, Types: map[string]int{"TODO": 3, "BUG": 1}},
+	}
+
+	svg := renderHistoryChart(history)
+
+	for _, want := range []string{"<svg", "total", "TODO", "BUG", "2024-05-01", "2024-06-01", "</svg>"} {
+		if !bytes.Contains(svg, []byte(want)) {
+			t.Fatalf("renderHistoryChart() does not contain %q", want)
+		}
+	}
+
+	if got := bytes.Count(svg, []byte("<polyline")); got != 3 {
+		t.Fatalf("renderHistoryChart() polylines = %d, want 3", got)
+	}
+}
+
+func TestRecordHistoryOrphanBranch(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	requests := make([]string, 0)
+	var history []*historySnapshot
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r":
+			_, _ = w.Write([]byte(`{"default_branch":"main"}`))
+		case "GET /repos/o/r/git/ref/heads/tdg-history":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "POST /repos/o/r/git/trees":
+			var req struct {
+				BaseTree string              `json:"base_tree"`
+				Tree     []*github.TreeEntry `json:"tree"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			if len(req.BaseTree) > 0 || len(req.Tree) != 1 || req.Tree[0].GetPath() != defaultHistoryFile {
+				t.Errorf("createTree() = %+v", req)
+			}
+			_, _ = w.Write([]byte(`{"sha":"tree"}`))
+		case "POST /repos/o/r/git/commits":
+			var req map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			if _, ok := req["parents"]; ok || req["tree"] != "tree" {
+				t.Errorf("createCommit() = %v", req)
+			}
+			_, _ = w.Write([]byte(`{"sha":"orphan"}`))
+		case "POST /repos/o/r/git/refs":
+			var ref map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&ref); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			if ref["sha"] != "orphan" {
+				t.Errorf("createRef() = %v", ref)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		case "GET /repos/o/r/contents/" + defaultHistoryFile:
+			content := base64.StdEncoding.EncodeToString([]byte("[]\n"))
+			_, _ = w.Write([]byte(`{"type":"file","encoding":"base64","sha":"f","content":"` + content + `"}`))
+		case "PUT /repos/o/r/contents/" + defaultHistoryFile:
+			var opt github.RepositoryContentFileOptions
+			if err := json.NewDecoder(r.Body).Decode(&opt); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			if err := json.Unmarshal(opt.Content, &history); err != nil {
+				t.Errorf("Unmarshal() error = %v", err)
+			}
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "r", branch: "main", sha: "abc", historyBranch: "tdg-history", historyFile: defaultHistoryFile},
+		now:    now,
+	}
+
+	if err := s.recordHistory([]*tdglib.ToDoComment{{Type: "TODO"}}); err != nil {
+		t.Fatalf("recordHistory() error = %v", err)
+	}
+
+	if !strings.Contains(strings.Join(requests, ","), "POST /repos/o/r/git/commits") {
+		t.Fatalf("recordHistory() requests = %v", requests)
+	}
+
+	if len(history) != 1 || !history[0].Timestamp.Equal(now) || history[0].Total != 1 {
+		t.Fatalf("recordHistory() history = %+v", history)
+	}
+}
+
+func TestHistoryFileLarge(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/contents/" + defaultHistoryFile:
+			// files over 1 MB have no content
+			_, _ = w.Write([]byte(`{"type":"file","encoding":"none","sha":"big","content":""}`))
+		case "GET /repos/o/r/git/blobs/big":
+			_, _ = w.Write([]byte(`[{"total":1}]`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "r", historyBranch: "tdg-history"},
+	}
+
+	data, sha, err := s.historyFile(defaultHistoryFile)
+	if err != nil || string(data) != `[{"total":1}]` || sha == nil || *sha != "big" {
+		t.Fatalf("historyFile() = %q, %v, %v", data, sha, err)
+	}
+}
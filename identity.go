@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+)
+
+var mailmapEmailRE = regexp.MustCompile(`<([^>]*)>`)
+
+// parseMailmap returns mapping from commit email to canonical email,
+// entries that only fix the name are not interesting for us
+func parseMailmap(content string) map[string]string {
+	mailmap := make(map[string]string)
+
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		emails := mailmapEmailRE.FindAllStringSubmatch(line, -1)
+		if len(emails) < 2 {
+			continue
+		}
+
+		proper := strings.TrimSpace(emails[0][1])
+		commit := strings.ToLower(strings.TrimSpace(emails[len(emails)-1][1]))
+		if len(proper) > 0 && len(commit) > 0 {
+			mailmap[commit] = proper
+		}
+	}
+
+	return mailmap
+}
+
+// parseAuthorsMap parses "email login" lines
+func parseAuthorsMap(content string) map[string]string {
+	authors := make(map[string]string)
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			log.Printf("Skipping invalid authors map line. line=%v", line)
+			continue
+		}
+
+		authors[strings.ToLower(fields[0])] = strings.TrimPrefix(fields[1], "@")
+	}
+
+	return authors
+}
+
+func (s *service) loadIdentities() {
+	if content, err := os.ReadFile(filepath.Join(workspaceRoot(), ".mailmap")); err == nil {
+		s.mailmap = parseMailmap(string(content))
+		log.Printf("Loaded .mailmap. count=%v", len(s.mailmap))
+	}
+
+	if len(s.env.authorsFile) > 0 {
+		content, err := os.ReadFile(s.env.authorsFile)
+		if err != nil {
+			log.Printf("Error while reading authors map. path=%v err=%v", s.env.authorsFile, err)
+			return
+		}
+
+		s.authorsMap = parseAuthorsMap(string(content))
+		log.Printf("Loaded authors map. count=%v", len(s.authorsMap))
+	}
+}
+
+// resolveEmail finds GitHub login for the commit email using .mailmap,
+// explicit authors map and user search in that order
+func (s *service) resolveEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) == 0 {
+		return ""
+	}
+
+	if login, ok := s.emailToLogin[email]; ok {
+		return login
+	}
+
+	canonical := email
+	if proper, ok := s.mailmap[email]; ok {
+		canonical = strings.ToLower(proper)
+	}
+
+	login := ""
+	for _, e := range []string{canonical, email} {
+		if l, ok := s.authorsMap[e]; ok {
+			login = l
+			break
+		}
+	}
+
+	if len(login) == 0 {
+		result, _, err := s.client.searchUsers(s.ctx, fmt.Sprintf("%s in:email", canonical), &github.SearchOptions{})
+		if err != nil {
+			log.Printf("Error while searching user by email. email=%v err=%v", canonical, err)
+		} else if len(result.Users) == 1 {
+			login = result.Users[0].GetLogin()
+		}
+	}
+
+	s.emailToLogin[email] = login
+	if len(login) == 0 {
+		s.unresolvedAuthors[email] = true
+	} else {
+		log.Printf("Resolved author email. email=%v login=%v", email, login)
+	}
+
+	return login
+}
+
+func (s *service) reportUnresolvedAuthors() {
+	if len(s.unresolvedAuthors) == 0 {
+		return
+	}
+
+	emails := make([]string, 0, len(s.unresolvedAuthors))
+	for email := range s.unresolvedAuthors {
+		emails = append(emails, "- "+markdownEscape(email))
+	}
+	sort.Strings(emails)
+
+	s.report.addSection("Unresolved authors",
+		"These commit emails could not be matched to GitHub users. Add them to `.mailmap` or `AUTHORS_FILE`.\n\n"+strings.Join(emails, "\n"))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseMailmap(t *testing.T) {
+	mailmap := parseMailmap(`# comment
+Jane Doe <jane@example.com>
+<jane@example.com> <Jane@Old.Example.com>
+John Doe <john@example.com> Johnny <johnny@laptop.local> # laptop
+`)
+
+	want := map[string]string{
+		"jane@old.example.com": "jane@example.com",
+		"johnny@laptop.local":  "john@example.com",
+	}
+
+	if len(mailmap) != len(want) {
+		t.Fatalf("parseMailmap() = %v, want %v", mailmap, want)
+	}
+
+	for k, v := range want {
+		if mailmap[k] != v {
+			t.Fatalf("parseMailmap()[%q] = %q, want %q", k, mailmap[k], v)
+		}
+	}
+}
+
+func TestResolveEmail(t *testing.T) {
+	searches := make([]string, 0)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/users" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+			return
+		}
+
+		query := r.URL.Query().Get("q")
+		searches = append(searches, query)
+		if strings.HasPrefix(query, "public@example.com") {
+			_, _ = w.Write([]byte(`{"total_count":1,"items":[{"login":"public"}]}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"total_count":0,"items":[]}`))
+	})
+
+	s := &service{
+		ctx:               context.Background(),
+		client:            newTestGitHubAPI(t, handler),
+		report:            &report{},
+		mailmap:           parseMailmap("<jane@example.com> <jane@laptop.local>"),
+		authorsMap:        parseAuthorsMap("jane@example.com @janedoe\ninvalid-line"),
+		emailToLogin:      make(map[string]string),
+		unresolvedAuthors: make(map[string]bool),
+	}
+
+	cases := map[string]string{
+		"jane@laptop.local":  "janedoe",
+		"public@example.com": "public",
+		"ghost@example.com":  "",
+	}
+
+	for email, want := range cases {
+		if got := s.resolveEmail(email); got != want {
+			t.Fatalf("resolveEmail(%q) = %q, want %q", email, got, want)
+		}
+	}
+
+	// cached
+	s.resolveEmail("ghost@example.com")
+
+	if len(searches) != 2 {
+		t.Fatalf("resolveEmail() searches = %v, want 2", searches)
+	}
+
+	s.reportUnresolvedAuthors()
+	if summary := s.report.String(); !strings.Contains(summary, "ghost@example.com") || strings.Contains(summary, "public@example.com") {
+		t.Fatalf("reportUnresolvedAuthors() = %q", summary)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+// parseIssueTypes parses comma or newline separated "TODO=Task" pairs
+// into a map from the comment type to the issue type
+func parseIssueTypes(value string) map[string]string {
+	types := make(map[string]string)
+
+	for _, pair := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		commentType, issueType, ok := strings.Cut(pair, "=")
+		commentType = strings.ToUpper(strings.TrimSpace(commentType))
+		issueType = strings.TrimSpace(issueType)
+		if !ok || len(commentType) == 0 || len(issueType) == 0 {
+			log.Printf("Ignoring invalid issue type mapping. value=%v", pair)
+			continue
+		}
+
+		types[commentType] = issueType
+	}
+
+	return types
+}
+
+// loadIssueTypes keeps only mappings to the issue types of the organization.
+// Repositories owned by users have no issue types at all
+func (s *service) loadIssueTypes() {
+	mapping := parseIssueTypes(s.env.issueTypes)
+
+	orgTypes, _, err := s.client.listIssueTypes(s.ctx, s.env.issueOwner)
+	if err != nil {
+		log.Printf("Error while listing issue types. org=%v err=%v", s.env.issueOwner, err)
+		return
+	}
+
+	names := make(map[string]string, len(orgTypes))
+	for _, t := range orgTypes {
+		names[strings.ToLower(t.GetName())] = t.GetName()
+	}
+
+	unknown := make([]string, 0)
+	s.issueTypes = make(map[string]string, len(mapping))
+
+	for commentType, issueType := range mapping {
+		name, ok := names[strings.ToLower(issueType)]
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("| %s | %s |", markdownEscape(commentType), markdownEscape(issueType)))
+			continue
+		}
+
+		s.issueTypes[commentType] = name
+	}
+
+	log.Printf("Loaded issue types. count=%v unknown=%v", len(s.issueTypes), len(unknown))
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		s.report.addSection("Unknown issue types", "| Comment type | Issue type |\n|---|---|\n"+strings.Join(unknown, "\n"))
+	}
+}
+
+func (s *service) issueType(c *tdglib.ToDoComment) string {
+	return s.issueTypes[strings.ToUpper(c.Type)]
+}
+
+// updateIssueTypes corrects issue types of existing issues when the mapping
+// or the comment type changed. Types of unmapped comments are left as is
+func (s *service) updateIssueTypes(issueMap map[string]*github.Issue, comments []*tdglib.ToDoComment) {
+	count := 0
+
+	for _, c := range comments {
+		issueType := s.issueType(c)
+		if len(issueType) == 0 {
+			continue
+		}
+
+		i, ok := issueMap[c.Title]
+		if !ok || i.GetState() == "closed" || i.GetType().GetName() == issueType {
+			continue
+		}
+
+		log.Printf("About to change issue type. issue=%v from=%v to=%v", i.GetNumber(), i.GetType().GetName(), issueType)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		edited, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), &github.IssueRequest{Type: &issueType})
+		if err != nil {
+			log.Printf("Error while changing issue type. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		i.Type = edited.Type
+		count++
+	}
+
+	log.Printf("Updated issue types. count=%v", count)
+}
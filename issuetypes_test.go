@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestParseIssueTypes(t *testing.T) {
+	got := parseIssueTypes("todo=Task, FIXME = Bug\nBUG=Bug,invalid,HACK=")
+	want := map[string]string{"TODO": "Task", "FIXME": "Bug", "BUG": "Bug"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseIssueTypes() = %v, want %v", got, want)
+	}
+}
+
+func TestIssueTypes(t *testing.T) {
+	edited := make(map[string]string)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /orgs/o/issue-types":
+			_, _ = w.Write([]byte(`[{"name":"Task"},{"name":"Bug"}]`))
+		case "PATCH /repos/o/r/issues/1", "PATCH /repos/o/r/issues/2":
+			var req github.IssueRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			edited[r.URL.Path] = req.GetType()
+			_ = json.NewEncoder(w).Encode(&github.Issue{Type: &github.IssueType{Name: req.Type}})
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{issueOwner: "o", issueRepo: "r", issueTypes: "TODO=task,FIXME=Bug,HACK=Chore"},
+		report: &report{},
+	}
+
+	s.loadIssueTypes()
+
+	want := map[string]string{"TODO": "Task", "FIXME": "Bug"}
+	if !reflect.DeepEqual(s.issueTypes, want) {
+		t.Fatalf("loadIssueTypes() = %v, want %v", s.issueTypes, want)
+	}
+
+	if len(s.report.sections) != 1 {
+		t.Fatalf("loadIssueTypes() report sections = %v", len(s.report.sections))
+	}
+
+	issueMap := map[string]*github.Issue{
+		"first":  {Number: github.Ptr(1), Type: &github.IssueType{Name: github.Ptr("Task")}},
+		"second": {Number: github.Ptr(2), Type: &github.IssueType{Name: github.Ptr("Task")}},
+		"third":  {Number: github.Ptr(3)},
+	}
+
+	s.updateIssueTypes(issueMap, []*tdglib.ToDoComment{
+		{Title: "first", Type: "TODO"},
+		{Title: "second", Type: "FIXME"},
+		{Title: "third", Type: "HACK"},
+	})
+
+	if !reflect.DeepEqual(edited, map[string]string{"/repos/o/r/issues/2": "Bug"}) {
+		t.Fatalf("updateIssueTypes() edited = %v", edited)
+	}
+
+	if issueMap["second"].GetType().GetName() != "Bug" {
+		t.Fatalf("updateIssueTypes() type = %v", issueMap["second"].GetType().GetName())
+	}
+}
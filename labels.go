@@ -0,0 +1,324 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const labelWildcard = "*"
+
+type labelStyle struct {
+	color       string
+	description string
+}
+
+// labelStyles maps label names or prefixes ending with "*" to the style
+type labelStyles map[string]*labelStyle
+
+func normalizeColor(color string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(color), "#"))
+}
+
+// defaultLabelStyles describes labels created by the action
+func (e *env) defaultLabelStyles() labelStyles {
+	return labelStyles{
+		e.label:                             {"7057ff", "Created from a TODO comment"},
+		labelBranchPrefix + labelWildcard:   {"c5def5", "Branch with the TODO comment"},
+		labelTypePrefix + labelWildcard:     {"fbca04", "Type of the TODO comment"},
+		labelAreaPrefix + labelWildcard:     {"0e8a16", "Category of the TODO comment"},
+		labelLangPrefix + labelWildcard:     {"1d76db", "Language of the file with the TODO comment"},
+		labelEstimatePrefix + labelWildcard: {"d4c5f9", "Estimate of the TODO comment"},
+		labelAgePrefix + labelWildcard:      {"bfd4f2", "Age of the TODO comment"},
+		labelPriorityPrefix + labelWildcard: {"d93f0b", "Priority of the TODO comment"},
+		e.staleLabel:                        {"cfd3d7", "TODO comment is stale"},
+		e.escalateLabel:                     {"b60205", "Stale TODO comment was escalated"},
+		e.overdueLabel:                      {"e99695", "TODO comment is past its due date"},
+	}
+}
+
+// parseLabelStyles parses "name|color|description" lines on top of the defaults
+func parseLabelStyles(value string, styles labelStyles) labelStyles {
+	for _, line := range strings.Split(value, "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		parts := strings.SplitN(line, "|", 3)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) < 2 || len(name) == 0 {
+			log.Printf("Ignoring invalid label style. value=%v", line)
+			continue
+		}
+
+		style := &labelStyle{color: normalizeColor(parts[1])}
+		if len(parts) == 3 {
+			style.description = strings.TrimSpace(parts[2])
+		}
+
+		styles[name] = style
+	}
+
+	return styles
+}
+
+// style returns style of the exact name or of the longest matching prefix
+func (ls labelStyles) style(name string) (*labelStyle, bool) {
+	if style, ok := ls[name]; ok {
+		return style, true
+	}
+
+	var (
+		best   *labelStyle
+		length int
+	)
+
+	for pattern, style := range ls {
+		prefix, ok := strings.CutSuffix(pattern, labelWildcard)
+		if ok && strings.HasPrefix(name, prefix) && len(prefix) >= length {
+			best, length = style, len(prefix)
+		}
+	}
+
+	return best, best != nil
+}
+
+func (s *service) listAllLabels() ([]*github.Label, error) {
+	var all []*github.Label
+
+	opt := &github.ListOptions{PerPage: defaultIssuesPerPage}
+	for {
+		labels, resp, err := s.client.listLabels(s.ctx, s.env.issueOwner, s.env.issueRepo, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, labels...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// ensureLabels creates labels the comments need and fixes colors and
+// descriptions of existing labels that drifted from their styles
+func (s *service) ensureLabels(comments []*tdglib.ToDoComment) {
+	styles := parseLabelStyles(s.env.labelStyles, s.env.defaultLabelStyles())
+
+	existing, err := s.listAllLabels()
+	if err != nil {
+		log.Printf("Error while listing labels. err=%v", err)
+		return
+	}
+
+	// label names are case insensitive
+	needed := map[string]string{strings.ToLower(s.env.label): s.env.label}
+	for _, c := range comments {
+		if s.snoozed[c] {
+			continue
+		}
+
+		for _, l := range s.labels(c) {
+			needed[strings.ToLower(l)] = l
+		}
+	}
+
+	created, updated := 0, 0
+
+	for _, l := range existing {
+		delete(needed, strings.ToLower(l.GetName()))
+
+		style, ok := styles.style(l.GetName())
+		if !ok || (normalizeColor(l.GetColor()) == style.color && l.GetDescription() == style.description) {
+			continue
+		}
+
+		log.Printf("About to update a label. name=%v color=%v", l.GetName(), style.color)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		req := &github.Label{Color: &style.color, Description: &style.description}
+		if _, _, err := s.client.editLabel(s.ctx, s.env.issueOwner, s.env.issueRepo, l.GetName(), req); err != nil {
+			log.Printf("Error while updating a label. name=%v err=%v", l.GetName(), err)
+			continue
+		}
+
+		updated++
+	}
+
+	names := make([]string, 0, len(needed))
+	for _, name := range needed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// labels without style are left to be created by GitHub
+		style, ok := styles.style(name)
+		if !ok {
+			continue
+		}
+
+		log.Printf("About to create a label. name=%v color=%v", name, style.color)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		req := &github.Label{Name: github.Ptr(name), Color: &style.color, Description: &style.description}
+		if _, _, err := s.client.createLabel(s.ctx, s.env.issueOwner, s.env.issueRepo, req); err != nil {
+			log.Printf("Error while creating a label. name=%v err=%v", name, err)
+			continue
+		}
+
+		created++
+	}
+
+	log.Printf("Ensured labels. created=%v updated=%v", created, updated)
+}
+
+// branchExists checks the branch in the code repository
+func (s *service) branchExists(branch string) (bool, error) {
+	_, _, err := s.client.getRef(s.ctx, s.env.codeOwner, s.env.codeRepo, "heads/"+branch)
+	if err != nil && !isNotFoundGitHubError(err) {
+		return false, err
+	}
+
+	return err == nil, nil
+}
+
+// labelInUse checks all open issues and not only the ones with LABEL
+// since the label could be added to other issues by hand
+func (s *service) labelInUse(name string) (bool, error) {
+	opt := &github.IssueListByRepoOptions{
+		Labels:      []string{name},
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 1},
+	}
+
+	issues, _, err := s.client.listByRepo(s.ctx, s.env.issueOwner, s.env.issueRepo, opt)
+	if err != nil {
+		return false, err
+	}
+
+	return len(issues) > 0, nil
+}
+
+// pruneLabels deletes branch and estimate labels that are not used by any
+// open issue or comment. Branch labels are kept while the branch exists
+func (s *service) pruneLabels(issues []*github.Issue, comments []*tdglib.ToDoComment) {
+	existing, err := s.listAllLabels()
+	if err != nil {
+		log.Printf("Error while listing labels. err=%v", err)
+		return
+	}
+
+	used := make(map[string]bool)
+	for _, c := range comments {
+		for _, l := range s.labels(c) {
+			used[strings.ToLower(l)] = true
+		}
+	}
+
+	openIssues := make([]*github.Issue, 0, len(issues)+len(s.newIssuesMap))
+	openIssues = append(openIssues, issues...)
+	for _, i := range s.newIssuesMap {
+		openIssues = append(openIssues, i)
+	}
+
+	for _, i := range openIssues {
+		if i.GetState() == "closed" {
+			continue
+		}
+
+		for _, l := range labelNames(i) {
+			used[strings.ToLower(l)] = true
+		}
+	}
+
+	sort.Slice(existing, func(a, b int) bool { return existing[a].GetName() < existing[b].GetName() })
+
+	pruned := make([]string, 0)
+	for _, l := range existing {
+		name := l.GetName()
+		if !strings.HasPrefix(name, labelBranchPrefix) && !strings.HasPrefix(name, labelEstimatePrefix) {
+			continue
+		}
+
+		if used[strings.ToLower(name)] {
+			continue
+		}
+
+		if branch, ok := strings.CutPrefix(name, labelBranchPrefix); ok {
+			exists, err := s.branchExists(branch)
+			if err != nil {
+				log.Printf("Error while checking branch. branch=%v err=%v", branch, err)
+				continue
+			}
+
+			if exists {
+				continue
+			}
+		}
+
+		inUse, err := s.labelInUse(name)
+		if err != nil {
+			log.Printf("Error while checking label usage. name=%v err=%v", name, err)
+			continue
+		}
+
+		if inUse {
+			continue
+		}
+
+		if s.env.pruneLimit > 0 && len(pruned) >= s.env.pruneLimit {
+			log.Printf("Exceeded limit of labels to prune. limit=%v", s.env.pruneLimit)
+			break
+		}
+
+		log.Printf("About to delete a label. name=%v", name)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			pruned = append(pruned, name)
+			continue
+		}
+
+		if _, err := s.client.deleteLabel(s.ctx, s.env.issueOwner, s.env.issueRepo, name); err != nil {
+			log.Printf("Error while deleting a label. name=%v err=%v", name, err)
+			continue
+		}
+
+		pruned = append(pruned, name)
+	}
+
+	log.Printf("Pruned labels. count=%v", len(pruned))
+
+	if len(pruned) == 0 {
+		return
+	}
+
+	title := "Pruned labels"
+	if s.env.dryRun {
+		title += " (dry run)"
+	}
+
+	lines := make([]string, 0, len(pruned))
+	for _, name := range pruned {
+		lines = append(lines, "- `"+name+"`")
+	}
+
+	s.report.addSection(title, strings.Join(lines, "\n"))
+}
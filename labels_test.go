@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestLabelStyles(t *testing.T) {
+	styles := parseLabelStyles("type: *|#AABBCC|Comment type\ntype: bug|ff0000|\ninvalid\n", labelStyles{})
+
+	tests := map[string]*labelStyle{
+		"type: todo": {"aabbcc", "Comment type"},
+		"type: bug":  {"ff0000", ""},
+	}
+
+	for name, want := range tests {
+		got, ok := styles.style(name)
+		if !ok || !reflect.DeepEqual(got, want) {
+			t.Fatalf("style(%v) = %+v, want %+v", name, got, want)
+		}
+	}
+
+	if _, ok := styles.style("area: x"); ok {
+		t.Fatalf("style() matched label without style")
+	}
+}
+
+func TestEnsureLabels(t *testing.T) {
+	created := make([]string, 0)
+	edited := make(map[string]*github.Label)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/labels":
+			_, _ = w.Write([]byte(`[
+				{"name":"TODO","color":"7057ff","description":"Created from a TODO comment"},
+				{"name":"type: todo","color":"ededed"},
+				{"name":"bug","color":"d73a4a"}
+			]`))
+		case "POST /repos/o/r/labels":
+			var l github.Label
+			if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			created = append(created, l.GetName()+" "+l.GetColor())
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		case "PATCH /repos/o/r/labels/type: todo":
+			var l github.Label
+			if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			edited["type: todo"] = &l
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{issueOwner: "o", issueRepo: "r", label: "todo", branch: "main", extendedLabels: true, labelStyles: "branch: main|000000|Default branch"},
+	}
+
+	s.ensureLabels([]*tdglib.ToDoComment{{Type: "TODO", File: "main.go"}})
+
+	want := []string{"branch: main 000000", "lang: go 1d76db"}
+	if !reflect.DeepEqual(created, want) {
+		t.Fatalf("ensureLabels() created = %v, want %v", created, want)
+	}
+
+	if l := edited["type: todo"]; l == nil || l.GetColor() != "fbca04" || l.GetDescription() != "Type of the TODO comment" {
+		t.Fatalf("ensureLabels() edited = %+v", edited)
+	}
+}
+
+func TestPruneLabels(t *testing.T) {
+	deleted := make([]string, 0)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/labels":
+			_, _ = w.Write([]byte(`[
+				{"name":"branch: main"},
+				{"name":"branch: merged"},
+				{"name":"branch: alive"},
+				{"name":"branch: gone"},
+				{"name":"estimate: 45m"},
+				{"name":"estimate: 2h"},
+				{"name":"estimate: 1h"},
+				{"name":"area: old"}
+			]`))
+		case "GET /repos/o/r/git/ref/heads/merged", "GET /repos/o/r/git/ref/heads/gone":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "GET /repos/o/r/git/ref/heads/alive":
+			_, _ = w.Write([]byte(`{"ref":"refs/heads/alive"}`))
+		case "GET /repos/o/r/issues":
+			if r.URL.Query().Get("labels") == "estimate: 2h" {
+				_, _ = w.Write([]byte(`[{"number":9}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[]`))
+		case "DELETE /repos/o/r/labels/branch: gone", "DELETE /repos/o/r/labels/branch: merged":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:          context.Background(),
+		client:       newTestGitHubAPI(t, handler),
+		env:          &env{codeOwner: "o", codeRepo: "r", issueOwner: "o", issueRepo: "r", label: "todo", branch: "main", extendedLabels: true, pruneLimit: 2},
+		report:       &report{},
+		newIssuesMap: make(map[string]*github.Issue),
+	}
+
+	issues := []*github.Issue{
+		{State: github.Ptr("open"), Labels: []*github.Label{{Name: github.Ptr("estimate: 1h")}}},
+		{State: github.Ptr("closed"), Labels: []*github.Label{{Name: github.Ptr("branch: gone")}}},
+	}
+
+	s.pruneLabels(issues, []*tdglib.ToDoComment{{Type: "TODO"}})
+
+	// "estimate: 45m" is not used either but the limit is reached
+	want := []string{"/repos/o/r/labels/branch: gone", "/repos/o/r/labels/branch: merged"}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Fatalf("pruneLabels() deleted = %v, want %v", deleted, want)
+	}
+
+	if len(s.report.sections) != 1 {
+		t.Fatalf("pruneLabels() report sections = %v", len(s.report.sections))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/v73/github"
 	"gitlab.com/ribtoks/tdg/pkg/tdglib"
@@ -28,6 +29,7 @@ const (
 	defaultCloseLimit    = 0
 	defaultIssuesPerPage = 200
 	defaultConcurrency   = 128
+	defaultBlameRemote   = "origin"
 	contextLinesUp       = 3
 	contextLinesDown     = 7
 	minEstimate          = 0.01
@@ -36,6 +38,7 @@ const (
 	labelTypePrefix      = "type: "
 	labelAreaPrefix      = "area: "
 	labelLangPrefix      = "lang: "
+	labelEstimatePrefix  = "estimate: "
 )
 
 func workspaceRoot() string {
@@ -56,28 +59,64 @@ func sourceRoot(root string) string {
 }
 
 type env struct {
-	root              string
-	codeOwner         string
-	codeRepo          string
-	issueOwner        string
-	issueRepo         string
-	label             string
-	token             string
-	sha               string
-	ref               string
-	branch            string
-	includeRE         string
-	excludeRE         string
-	minWords          int
-	minChars          int
-	addLimit          int
-	closeLimit        int
-	concurrency       int
-	closeOnSameBranch bool
-	extendedLabels    bool
-	dryRun            bool
-	commentIssue      bool
-	assignFromBlame   bool
+	root                string
+	codeOwner           string
+	codeRepo            string
+	issueOwner          string
+	issueRepo           string
+	label               string
+	token               string
+	sha                 string
+	ref                 string
+	branch              string
+	includeRE           string
+	excludeRE           string
+	codeQualityReport   string
+	historyBranch       string
+	historyFile         string
+	historyChart        string
+	badgesDir           string
+	badgeStyle          string
+	badgeColor          string
+	authorsFile         string
+	blameRemote         string
+	baseSHA             string
+	projectOwner        string
+	projectStatus       string
+	projectDoneStatus   string
+	issueTypes          string
+	trackingIssues      string
+	epics               string
+	discussionCategory  string
+	discussionTypes     string
+	labelStyles         string
+	migrateLabels       string
+	staleLabel          string
+	escalateLabel       string
+	overdueLabel        string
+	minWords            int
+	minChars            int
+	addLimit            int
+	closeLimit          int
+	concurrency         int
+	staleDays           int
+	escalateDays        int
+	blameFetchDepth     int
+	projectNumber       int
+	pruneLimit          int
+	closeOnSameBranch   bool
+	extendedLabels      bool
+	dryRun              bool
+	commentIssue        bool
+	commentPullRequests bool
+	subIssues           bool
+	assignFromBlame     bool
+	assignFromAuthor    bool
+	assignFromOwners    bool
+	ageReport           bool
+	dueMilestones       bool
+	manageLabels        bool
+	pruneLabels         bool
 }
 
 type service struct {
@@ -89,6 +128,32 @@ type service struct {
 	newIssuesMap            map[string]*github.Issue
 	issueTitleToAssigneeMap map[string]string
 	commitToAuthorCache     map[string]string
+	blames                  map[*tdglib.ToDoComment]*blameInfo
+	safeDirectoryOnce       sync.Once
+	shallow                 bool
+	shallowOnce             sync.Once
+	shallowCommits          map[string]bool
+	shallowCommitsOnce      sync.Once
+	metadata                map[*tdglib.ToDoComment]map[string]string
+	dueDates                map[*tdglib.ToDoComment]time.Time
+	milestones              map[string]*github.Milestone
+	snoozed                 map[*tdglib.ToDoComment]bool
+	tags                    map[string]bool
+	assignables             map[string]bool
+	parents                 map[int]bool
+	issueTypes              map[string]string
+	metadataProblems        []string
+	metadataMux             sync.Mutex
+	codeowners              *codeowners
+	project                 *project
+	mailmap                 map[string]string
+	authorsMap              map[string]string
+	emailToLogin            map[string]string
+	unresolvedAuthors       map[string]bool
+	report                  *report
+	now                     time.Time
+	createdCount            int
+	closedCount             int
 }
 
 func (e *env) sourceRoot() string {
@@ -112,23 +177,109 @@ func environment() *env {
 
 	ref := os.Getenv("INPUT_REF")
 	e := &env{
-		ref:               ref,
-		codeOwner:         cr[0],
-		codeRepo:          cr[1],
-		issueOwner:        ir[0],
-		issueRepo:         ir[1],
-		branch:            branch(ref),
-		sha:               os.Getenv("INPUT_SHA"),
-		root:              os.Getenv("INPUT_ROOT"),
-		label:             os.Getenv("INPUT_LABEL"),
-		token:             os.Getenv("INPUT_TOKEN"),
-		includeRE:         os.Getenv("INPUT_INCLUDE_PATTERN"),
-		excludeRE:         os.Getenv("INPUT_EXCLUDE_PATTERN"),
-		dryRun:            flagToBool(os.Getenv("INPUT_DRY_RUN")),
-		extendedLabels:    flagToBool(os.Getenv("INPUT_EXTENDED_LABELS")),
-		closeOnSameBranch: flagToBool(os.Getenv("INPUT_CLOSE_ON_SAME_BRANCH")),
-		commentIssue:      flagToBool(os.Getenv("INPUT_COMMENT_ON_ISSUES")),
-		assignFromBlame:   flagToBool(os.Getenv("INPUT_ASSIGN_FROM_BLAME")),
+		ref:                 ref,
+		codeOwner:           cr[0],
+		codeRepo:            cr[1],
+		issueOwner:          ir[0],
+		issueRepo:           ir[1],
+		branch:              branch(ref),
+		sha:                 os.Getenv("INPUT_SHA"),
+		root:                os.Getenv("INPUT_ROOT"),
+		label:               os.Getenv("INPUT_LABEL"),
+		token:               os.Getenv("INPUT_TOKEN"),
+		includeRE:           os.Getenv("INPUT_INCLUDE_PATTERN"),
+		excludeRE:           os.Getenv("INPUT_EXCLUDE_PATTERN"),
+		codeQualityReport:   os.Getenv("INPUT_CODE_QUALITY_REPORT"),
+		historyBranch:       os.Getenv("INPUT_HISTORY_BRANCH"),
+		historyFile:         os.Getenv("INPUT_HISTORY_FILE"),
+		historyChart:        os.Getenv("INPUT_HISTORY_CHART"),
+		badgesDir:           os.Getenv("INPUT_BADGES_DIR"),
+		badgeStyle:          os.Getenv("INPUT_BADGE_STYLE"),
+		badgeColor:          os.Getenv("INPUT_BADGE_COLOR"),
+		authorsFile:         os.Getenv("INPUT_AUTHORS_FILE"),
+		blameRemote:         os.Getenv("INPUT_BLAME_REMOTE"),
+		baseSHA:             os.Getenv("INPUT_BASE_SHA"),
+		projectOwner:        os.Getenv("INPUT_PROJECT_OWNER"),
+		projectStatus:       os.Getenv("INPUT_PROJECT_STATUS"),
+		projectDoneStatus:   os.Getenv("INPUT_PROJECT_DONE_STATUS"),
+		issueTypes:          os.Getenv("INPUT_ISSUE_TYPES"),
+		trackingIssues:      strings.ToLower(os.Getenv("INPUT_TRACKING_ISSUES")),
+		epics:               strings.ToLower(os.Getenv("INPUT_EPICS")),
+		discussionCategory:  os.Getenv("INPUT_DISCUSSION_CATEGORY"),
+		discussionTypes:     os.Getenv("INPUT_DISCUSSION_TYPES"),
+		labelStyles:         os.Getenv("INPUT_LABEL_STYLES"),
+		migrateLabels:       os.Getenv("INPUT_MIGRATE_LABELS"),
+		staleLabel:          os.Getenv("INPUT_STALE_LABEL"),
+		escalateLabel:       os.Getenv("INPUT_ESCALATE_LABEL"),
+		overdueLabel:        os.Getenv("INPUT_OVERDUE_LABEL"),
+		dryRun:              flagToBool(os.Getenv("INPUT_DRY_RUN")),
+		extendedLabels:      flagToBool(os.Getenv("INPUT_EXTENDED_LABELS")),
+		closeOnSameBranch:   flagToBool(os.Getenv("INPUT_CLOSE_ON_SAME_BRANCH")),
+		commentIssue:        flagToBool(os.Getenv("INPUT_COMMENT_ON_ISSUES")),
+		commentPullRequests: flagToBool(os.Getenv("INPUT_COMMENT_ON_PULL_REQUESTS")),
+		subIssues:           flagToBool(os.Getenv("INPUT_SUB_ISSUES")),
+		assignFromBlame:     flagToBool(os.Getenv("INPUT_ASSIGN_FROM_BLAME")),
+		assignFromAuthor:    flagToBool(os.Getenv("INPUT_ASSIGN_FROM_AUTHOR")),
+		assignFromOwners:    flagToBool(os.Getenv("INPUT_ASSIGN_FROM_CODEOWNERS")),
+		ageReport:           flagToBool(os.Getenv("INPUT_AGE_REPORT")),
+		dueMilestones:       flagToBool(os.Getenv("INPUT_DUE_MILESTONES")),
+		manageLabels:        flagToBool(os.Getenv("INPUT_MANAGE_LABELS")),
+		pruneLabels:         flagToBool(os.Getenv("INPUT_PRUNE_LABELS")),
+	}
+
+	if len(e.historyFile) == 0 {
+		e.historyFile = defaultHistoryFile
+	}
+
+	if e.badgeStyle != badgeStyleFlatSquare {
+		e.badgeStyle = badgeStyleFlat
+	}
+
+	if len(e.badgeColor) == 0 {
+		e.badgeColor = defaultBadgeColor
+	}
+
+	if len(e.staleLabel) == 0 {
+		e.staleLabel = defaultStaleLabel
+	}
+
+	if len(e.escalateLabel) == 0 {
+		e.escalateLabel = defaultEscalateLabel
+	}
+
+	if len(e.overdueLabel) == 0 {
+		e.overdueLabel = defaultOverdueLabel
+	}
+
+	if len(e.blameRemote) == 0 {
+		e.blameRemote = defaultBlameRemote
+	}
+
+	if len(e.projectOwner) == 0 {
+		e.projectOwner = e.issueOwner
+	}
+
+	if len(e.projectStatus) == 0 {
+		e.projectStatus = defaultProjectStatus
+	}
+
+	if len(e.projectDoneStatus) == 0 {
+		e.projectDoneStatus = defaultProjectDoneStatus
+	}
+
+	if len(e.trackingIssues) > 0 && !isTrackingMode(e.trackingIssues) {
+		log.Printf("Unknown tracking issues mode. mode=%v", e.trackingIssues)
+		e.trackingIssues = ""
+	}
+
+	if len(e.epics) > 0 && !isEpicsMode(e.epics) {
+		log.Printf("Unknown epics mode. mode=%v", e.epics)
+		e.epics = ""
+	}
+
+	// push of a new branch has no previous commit
+	if strings.Trim(e.baseSHA, "0") == "" {
+		e.baseSHA = ""
 	}
 
 	var err error
@@ -158,14 +309,50 @@ func environment() *env {
 		e.concurrency = defaultConcurrency
 	}
 
+	e.staleDays, err = strconv.Atoi(os.Getenv("INPUT_STALE_DAYS"))
+	if err != nil {
+		e.staleDays = 0
+	}
+
+	e.escalateDays, err = strconv.Atoi(os.Getenv("INPUT_ESCALATE_DAYS"))
+	if err != nil {
+		e.escalateDays = 0
+	}
+
+	e.blameFetchDepth, err = strconv.Atoi(os.Getenv("INPUT_BLAME_FETCH_DEPTH"))
+	if err != nil {
+		e.blameFetchDepth = 0
+	}
+
+	e.projectNumber, err = strconv.Atoi(os.Getenv("INPUT_PROJECT_NUMBER"))
+	if err != nil {
+		e.projectNumber = 0
+	}
+
+	e.pruneLimit, err = strconv.Atoi(os.Getenv("INPUT_PRUNE_LIMIT"))
+	if err != nil {
+		e.pruneLimit = 0
+	}
+
 	return e
 }
 
+// needsAssignees returns true if new issues are assigned after creation
+func (e *env) needsAssignees() bool {
+	return e.assignFromBlame || e.assignFromAuthor || e.assignFromOwners
+}
+
+// needsBlame returns true if git blame details of all comments are used
+func (e *env) needsBlame() bool {
+	return e.ageReport || e.assignFromBlame || e.assignFromAuthor
+}
+
 func (e *env) debugPrint() {
 	log.Printf("Code repo: %v", e.codeRepo)
 	log.Printf("Issue repo: %v", e.issueRepo)
 	log.Printf("Ref: %v", e.ref)
 	log.Printf("Sha: %v", e.sha)
+	log.Printf("Base sha: %v", e.baseSHA)
 	log.Printf("Root: %v", e.root)
 	log.Printf("Branch: %v", e.branch)
 	log.Printf("Label: %v", e.label)
@@ -176,6 +363,23 @@ func (e *env) debugPrint() {
 	log.Printf("Close limit: %v", e.closeLimit)
 	log.Printf("Close on same branch: %v", e.closeOnSameBranch)
 	log.Printf("Dry run: %v", e.dryRun)
+	log.Printf("Assign from blame: %v", e.assignFromBlame)
+	log.Printf("Assign from author: %v", e.assignFromAuthor)
+	log.Printf("Assign from CODEOWNERS: %v", e.assignFromOwners)
+	log.Printf("Blame fetch depth: %v", e.blameFetchDepth)
+	log.Printf("Project: %v/%v", e.projectOwner, e.projectNumber)
+	log.Printf("Issue types: %v", e.issueTypes)
+	log.Printf("Tracking issues: %v", e.trackingIssues)
+	log.Printf("Epics: %v", e.epics)
+	log.Printf("Discussion category: %v", e.discussionCategory)
+	log.Printf("Discussion types: %v", e.discussionTypes)
+	log.Printf("Age report: %v", e.ageReport)
+	log.Printf("Stale days: %v", e.staleDays)
+	log.Printf("Escalate days: %v", e.escalateDays)
+	log.Printf("Due milestones: %v", e.dueMilestones)
+	log.Printf("Code quality report: %v", e.codeQualityReport)
+	log.Printf("History branch: %v", e.historyBranch)
+	log.Printf("Badges dir: %v", e.badgesDir)
 }
 
 func branch(ref string) string {
@@ -235,6 +439,23 @@ func escapePath(path string) string {
 	return strings.Join(result, "/")
 }
 
+// repoPath returns path of the file relative to the repository root
+func (e *env) repoPath(file string) string {
+	root := e.root
+	root = strings.TrimPrefix(root, ".")
+	root = strings.TrimPrefix(root, "/")
+	root = strings.TrimSuffix(root, "/")
+
+	filepath := file
+	if (root != ".") && (root != "/") && (root != "") {
+		filepath = fmt.Sprintf("%v/%v", root, file)
+	} else if (root == "/") && !strings.HasPrefix(file, "/") {
+		filepath = "/" + file
+	}
+
+	return filepath
+}
+
 func (s *service) createFileLink(c *tdglib.ToDoComment) string {
 	start := c.Line - contextLinesUp
 	if start < 0 {
@@ -246,27 +467,27 @@ func (s *service) createFileLink(c *tdglib.ToDoComment) string {
 		end = maxLines
 	}
 
-	root := s.env.root
-	root = strings.TrimPrefix(root, ".")
-	root = strings.TrimPrefix(root, "/")
-	root = strings.TrimSuffix(root, "/")
-
-	filepath := c.File
-	if (root != ".") && (root != "/") && (root != "") {
-		filepath = fmt.Sprintf("%v/%v", root, c.File)
-	} else if (root == "/") && !strings.HasPrefix(c.File, "/") {
-		filepath = "/" + c.File
-	}
-
-	safeFilepath := escapePath(filepath)
+	safeFilepath := escapePath(s.env.repoPath(c.File))
 
 	// https://github.com/{repo}/blob/{sha}/{file}#L{startLines}-L{endLine}
 	return fmt.Sprintf("https://github.com/%s/%s/blob/%s/%s#L%v-L%v",
 		s.env.codeOwner, s.env.codeRepo, s.env.sha, safeFilepath, start, end)
 }
 
+// formatEstimate formats estimate in hours as minutes or hours
+func formatEstimate(estimate float64) string {
+	minutes := math.Round(estimate * hourMinutes)
+	if minutes >= hourMinutes {
+		// -1 means use the smallest number of digits
+		return strconv.FormatFloat(estimate, 'f', -1, 32) + "h"
+	}
+
+	return fmt.Sprintf("%vm", minutes)
+}
+
 func (s *service) labels(c *tdglib.ToDoComment) []string {
 	labels := []string{s.env.label}
+	labels = append(labels, s.metadataLabels(c)...)
 	if s.env.extendedLabels {
 		labels = append(labels, labelBranchPrefix+s.env.branch)
 		labels = append(labels, labelTypePrefix+strings.ToLower(c.Type))
@@ -280,17 +501,11 @@ func (s *service) labels(c *tdglib.ToDoComment) []string {
 		}
 
 		if c.Estimate > minEstimate {
-			minutes := math.Round(c.Estimate * hourMinutes)
-			estimate := ""
-
-			if minutes >= hourMinutes {
-				// -1 means use the smallest number of digits
-				estimate = strconv.FormatFloat(c.Estimate, 'f', -1, 32) + "h"
-			} else {
-				estimate = fmt.Sprintf("%vm", minutes)
-			}
+			labels = append(labels, labelEstimatePrefix+formatEstimate(c.Estimate))
+		}
 
-			labels = append(labels, fmt.Sprintf("estimate: %v", estimate))
+		if label, ok := s.ageLabel(c); ok {
+			labels = append(labels, label)
 		}
 	}
 
@@ -303,9 +518,24 @@ func (s *service) openNewIssues(issueMap map[string]*github.Issue, comments []*t
 
 	for _, c := range comments {
 		if _, ok := issueMap[c.Title]; !ok {
+			if s.snoozed[c] {
+				log.Printf("Skipping snoozed comment. title=%v", c.Title)
+				continue
+			}
+
+			// the parent is attached by updateSubIssues on the next run
+			parent, err := s.commentParent(c)
+			if err != nil {
+				log.Printf("Error while checking parent issue. issue=%v err=%v", c.Issue, err)
+			}
+
 			body := c.Body + "\n\n"
-			if c.Issue > 0 {
-				body += fmt.Sprintf("Parent issue: #%v\n", c.Issue)
+			if parent > 0 {
+				body += parentIssueLine(parent)
+			}
+
+			if due, ok := s.dueDates[c]; ok {
+				body += fmt.Sprintf("Due: %v\n", due.Format(dueDateLayout))
 			}
 
 			if len(c.Author) > 0 {
@@ -314,6 +544,10 @@ func (s *service) openNewIssues(issueMap map[string]*github.Issue, comments []*t
 				body += fmt.Sprintf("Author: %s\n", c.CommitterEmail)
 			}
 
+			if _, ok := s.metadata[c][assigneeMetadataKey]; !ok && len(c.Author) == 0 {
+				body += s.codeownersBody(s.env.repoPath(c.File))
+			}
+
 			body += fmt.Sprintf("Line: %v\n%s", c.Line, s.createFileLink(c))
 
 			log.Printf("About to create an issue. title=%v body=%v", c.Title, body)
@@ -330,6 +564,18 @@ func (s *service) openNewIssues(issueMap map[string]*github.Issue, comments []*t
 				Labels: &labels,
 			}
 
+			if milestone, ok := s.metadataMilestone(c); ok {
+				req.Milestone = &milestone
+			}
+
+			if assignee := s.metadataAssignee(c); len(assignee) > 0 {
+				req.Assignees = &[]string{assignee}
+			}
+
+			if issueType := s.issueType(c); len(issueType) > 0 {
+				req.Type = &issueType
+			}
+
 			issue, _, err := s.client.createIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, req)
 			if err != nil {
 				log.Printf("Error while creating an issue. err=%v", err)
@@ -339,6 +585,16 @@ func (s *service) openNewIssues(issueMap map[string]*github.Issue, comments []*t
 			s.newIssuesMap[c.Title] = issue
 			log.Printf("Created an issue. title=%v issue=%v", c.Title, issue.GetID())
 
+			if s.env.subIssues && parent > 0 {
+				if err := s.attachSubIssue(parent, issue); err != nil {
+					log.Printf("Error while attaching sub-issue. parent=%v issue=%v err=%v", parent, issue.GetNumber(), err)
+				}
+			}
+
+			if s.project != nil {
+				s.addToProject(issue, c)
+			}
+
 			count++
 			if s.env.addLimit > 0 && count >= s.env.addLimit {
 				log.Printf("Exceeded limit of issues to create. limit=%v", s.env.addLimit)
@@ -347,6 +603,7 @@ func (s *service) openNewIssues(issueMap map[string]*github.Issue, comments []*t
 		}
 	}
 
+	s.createdCount = count
 	log.Printf("Created new issues. count=%v", count)
 }
 
@@ -358,6 +615,10 @@ func (s *service) assignNewIssues() {
 			log.Printf("Skipping assigning an issue that was not created. title=%v", title)
 			continue
 		}
+		if len(issue.Assignees) > 0 {
+			log.Printf("Skipping assigning an issue that already has assignees. title=%v", title)
+			continue
+		}
 		issueNumber := issue.GetNumber()
 		req := &github.IssueRequest{
 			Assignees: &[]string{assignee},
@@ -374,17 +635,29 @@ func (s *service) retrieveCommitAuthor(commitHash string, title string) {
 	// First check cache to see if this commit was already retrieved before
 	if commitAuthor, ok := s.commitToAuthorCache[commitHash]; ok {
 		log.Printf("Found cached author='%v' for commit '%v'", commitAuthor, commitHash)
-		s.issueTitleToAssigneeMap[title] = commitAuthor
+		if len(commitAuthor) > 0 {
+			s.issueTitleToAssigneeMap[title] = commitAuthor
+		}
 		return
 	}
 
 	commit, _, err := s.client.getCommit(s.ctx, s.env.codeOwner, s.env.codeRepo, commitHash, &github.ListOptions{})
 	if err != nil {
 		log.Printf("Error while getting commit from commit hash. err=%v", err)
-	} else if commit != nil && commit.Author != nil && len(*commit.Author.Login) > 0 {
-		s.issueTitleToAssigneeMap[title] = *commit.Author.Login
-		s.commitToAuthorCache[commitHash] = *commit.Author.Login
-		log.Printf("Successfully got author '%v' for commit '%v'\nfor issue title '%v'.", *commit.Author.Login, commitHash, title)
+		return
+	}
+
+	// commits made with emails not linked to any account have no author
+	login := commit.GetAuthor().GetLogin()
+	if len(login) == 0 {
+		login = s.resolveEmail(commit.GetCommit().GetAuthor().GetEmail())
+	}
+
+	s.commitToAuthorCache[commitHash] = login
+
+	if len(login) > 0 {
+		s.issueTitleToAssigneeMap[title] = login
+		log.Printf("Successfully got author '%v' for commit '%v'\nfor issue title '%v'.", login, commitHash, title)
 	} else {
 		log.Printf("Error: No author mentioned in commit '%v'", commitHash)
 	}
@@ -395,8 +668,25 @@ func (s *service) retrieveNewIssueAssignees(issueMap map[string]*github.Issue, c
 
 	totalNewIssues := 0
 	for _, c := range comments {
-		if _, ok := issueMap[c.Title]; !ok {
+		if _, ok := issueMap[c.Title]; !ok && !s.snoozed[c] {
 			totalNewIssues++
+			if s.env.assignFromAuthor && len(c.Author) > 0 {
+				if s.assignable(c.Author) {
+					s.issueTitleToAssigneeMap[c.Title] = c.Author
+					continue
+				}
+
+				s.addMetadataProblem(c, fmt.Sprintf("author `%v` cannot be assigned", c.Author))
+			}
+
+			if s.env.assignFromOwners {
+				if owner := s.codeownersAssignee(s.env.repoPath(c.File)); len(owner) > 0 {
+					s.issueTitleToAssigneeMap[c.Title] = owner
+					continue
+				}
+			}
+
+			// blame is used as a fallback for the author
 			if len(c.CommitHash) > 0 {
 				s.retrieveCommitAuthor(c.CommitHash, c.Title)
 			}
@@ -404,6 +694,7 @@ func (s *service) retrieveNewIssueAssignees(issueMap map[string]*github.Issue, c
 	}
 
 	log.Printf("Got assignees for %v of %v new issues.", len(s.issueTitleToAssigneeMap), totalNewIssues)
+	s.reportUnresolvedAuthors()
 }
 
 func (s *service) canCloseIssue(issue *github.Issue) bool {
@@ -454,6 +745,7 @@ func (s *service) closeMissingIssues(issueMap map[string]*github.Issue, comments
 
 	count := 0
 	commentsMap := make(map[string]*tdglib.ToDoComment)
+	resolved := make(map[int][]*github.Issue)
 	closed := "closed"
 
 	for _, c := range comments {
@@ -483,12 +775,13 @@ func (s *service) closeMissingIssues(issueMap map[string]*github.Issue, comments
 			continue
 		}
 
-		if s.env.commentIssue {
-			commitRef := s.env.sha
-			if (s.env.codeRepo != s.env.issueRepo) || (s.env.codeOwner != s.env.issueOwner) {
-				commitRef = fmt.Sprintf("%s/%s@%s", s.env.codeOwner, s.env.codeRepo, s.env.sha)
+		var pr *github.PullRequest
+		if s.env.commentIssue || s.env.commentPullRequests {
+			var comment string
+			comment, pr = s.closingComment(i)
+			if s.env.commentIssue {
+				s.commentIssue(comment, i)
 			}
-			s.commentIssue(fmt.Sprintf("Closed in commit %v", commitRef), i)
 		}
 
 		req := &github.IssueRequest{
@@ -502,6 +795,15 @@ func (s *service) closeMissingIssues(issueMap map[string]*github.Issue, comments
 		}
 
 		log.Printf("Closed an issue. issue=%v", i.GetID())
+		i.State = &closed
+
+		if s.project != nil {
+			s.completeProjectItem(i)
+		}
+
+		if pr != nil && s.env.commentPullRequests {
+			resolved[pr.GetNumber()] = append(resolved[pr.GetNumber()], i)
+		}
 
 		count++
 		if s.env.closeLimit > 0 && count >= s.env.closeLimit {
@@ -510,6 +812,9 @@ func (s *service) closeMissingIssues(issueMap map[string]*github.Issue, comments
 		}
 	}
 
+	s.commentPullRequests(resolved)
+
+	s.closedCount = count
 	log.Printf("Closed issues. count=%v", count)
 }
 
@@ -552,15 +857,40 @@ func main() {
 		newIssuesMap:            make(map[string]*github.Issue),
 		issueTitleToAssigneeMap: make(map[string]string),
 		commitToAuthorCache:     make(map[string]string),
+		blames:                  make(map[*tdglib.ToDoComment]*blameInfo),
+		metadata:                make(map[*tdglib.ToDoComment]map[string]string),
+		dueDates:                make(map[*tdglib.ToDoComment]time.Time),
+		snoozed:                 make(map[*tdglib.ToDoComment]bool),
+		tags:                    make(map[string]bool),
+		assignables:             make(map[string]bool),
+		parents:                 make(map[int]bool),
+		emailToLogin:            make(map[string]string),
+		unresolvedAuthors:       make(map[string]bool),
+		report:                  &report{},
+		now:                     time.Now(),
 	}
 
 	env.debugPrint()
 
+	// migration is a separate run so that the next sync finds all issues
+	if len(env.migrateLabels) > 0 {
+		err := svc.migrateLabels()
+		if writeErr := svc.report.write(); writeErr != nil {
+			log.Printf("Error while writing job summary. err=%v", writeErr)
+		}
+		if err != nil {
+			log.Panic(err)
+		}
+		return
+	}
+
 	issues, err := svc.fetchGithubIssues()
 	if err != nil {
 		log.Panic(err)
 	}
 
+	issues, epics := splitEpicIssues(issues)
+
 	includePatterns := make([]string, 0)
 	if len(env.includeRE) > 0 {
 		includePatterns = append(includePatterns, env.includeRE)
@@ -574,7 +904,7 @@ func main() {
 	svc.tdg = tdglib.NewToDoGenerator(env.sourceRoot(),
 		includePatterns,
 		excludePatterns,
-		env.assignFromBlame,
+		false,
 		env.minWords,
 		env.minChars,
 		env.concurrency)
@@ -586,28 +916,146 @@ func main() {
 
 	log.Printf("Extracted TODO comments. count=%v", len(comments))
 
+	if len(env.codeQualityReport) > 0 {
+		if err := env.writeCodeClimateReport(comments); err != nil {
+			log.Printf("Error while writing code quality report. err=%v", err)
+		}
+	}
+
+	if len(env.badgesDir) > 0 {
+		if err := env.writeBadges(comments); err != nil {
+			log.Printf("Error while writing badges. err=%v", err)
+		}
+	}
+
+	// blame is done here once per file instead of once per comment in tdg
+	if env.needsBlame() {
+		svc.blameComments(comments)
+	}
+
+	if env.assignFromOwners {
+		svc.codeowners = loadCodeowners(workspaceRoot())
+	}
+
+	if env.needsAssignees() {
+		svc.loadIdentities()
+	}
+
+	svc.loadMetadata(comments)
+	svc.checkUnknownMetadata(comments)
+
+	if env.ageReport {
+		svc.reportAges(comments)
+	}
+
+	svc.resolveDueDates(comments)
+	svc.resolveSnoozed(comments)
+
+	if len(env.issueTypes) > 0 {
+		svc.loadIssueTypes()
+	}
+
+	if env.projectNumber > 0 {
+		if err := svc.loadProject(); err != nil {
+			log.Printf("Error while loading project. owner=%v number=%v err=%v", env.projectOwner, env.projectNumber, err)
+		}
+	}
+
+	// comments that go to discussions are not issues, unless the category
+	// is missing since otherwise their issues would be closed
+	allComments := comments
+	discussionComments := make([]*tdglib.ToDoComment, 0)
+
+	var category *discussionCategory
+	if len(env.discussionCategory) > 0 {
+		category, err = svc.loadDiscussionCategory()
+		if err != nil {
+			log.Printf("Error while loading discussion category. category=%v err=%v", env.discussionCategory, err)
+		} else {
+			svc.reportUnknownDiscussionTypes()
+			comments, discussionComments = svc.splitDiscussionComments(comments)
+		}
+	}
+
+	if env.manageLabels {
+		svc.ensureLabels(comments)
+	}
+
 	issueMap := make(map[string]*github.Issue)
 	for _, i := range issues {
 		issueMap[i.GetTitle()] = i
 	}
 
-	svc.wg.Add(1)
-	go svc.closeMissingIssues(issueMap, comments)
+	if env.closeOnSameBranch {
+		svc.rehomeMergedBranches(issues)
+	}
 
-	svc.wg.Add(1)
-	go svc.openNewIssues(issueMap, comments)
+	if len(env.trackingIssues) > 0 {
+		svc.updateTrackingIssues(issues, comments)
+	} else {
+		svc.wg.Add(1)
+		go svc.closeMissingIssues(issueMap, comments)
 
-	if env.assignFromBlame && !env.dryRun {
 		svc.wg.Add(1)
-		go svc.retrieveNewIssueAssignees(issueMap, comments)
+		go svc.openNewIssues(issueMap, comments)
+
+		if env.needsAssignees() && !env.dryRun {
+			svc.wg.Add(1)
+			go svc.retrieveNewIssueAssignees(issueMap, comments)
+		}
+
+		if env.ageReport && env.extendedLabels {
+			svc.wg.Add(1)
+			go svc.updateAgeLabels(issueMap, comments)
+		}
 	}
 
 	log.Printf("Waiting for issues management to finish")
 	svc.wg.Wait()
 
-	if env.assignFromBlame && !env.dryRun && len(svc.newIssuesMap) > 0 {
+	if category != nil {
+		svc.syncDiscussions(category, discussionComments)
+	}
+
+	svc.updateIssuesMetadata(issueMap, comments)
+
+	if env.subIssues {
+		svc.updateSubIssues(issueMap, comments)
+	}
+
+	if len(svc.issueTypes) > 0 {
+		svc.updateIssueTypes(issueMap, comments)
+	}
+
+	svc.markOverdueIssues(issueMap, comments)
+
+	if env.staleDays > 0 || env.escalateDays > 0 {
+		svc.escalateStaleIssues(issueMap, comments)
+	}
+
+	if env.needsAssignees() && !env.dryRun && len(svc.newIssuesMap) > 0 {
 		svc.assignNewIssues()
 	}
 
+	if len(env.epics) > 0 && len(env.trackingIssues) == 0 {
+		svc.updateEpics(epics, issues, comments)
+	}
+
+	if len(env.historyBranch) > 0 {
+		if err := svc.recordHistory(allComments); err != nil {
+			log.Printf("Error while recording history. err=%v", err)
+		}
+	}
+
+	if env.pruneLabels {
+		svc.pruneLabels(issues, comments)
+	}
+
+	svc.reportMetadataProblems()
+
+	if err := svc.report.write(); err != nil {
+		log.Printf("Error while writing job summary. err=%v", err)
+	}
+
 	appendGitHubActionOutput()
 }
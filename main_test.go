@@ -1,6 +1,13 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
 
 func TestSourceRootUsesGitHubWorkspace(t *testing.T) {
 	t.Setenv("GITHUB_WORKSPACE", "/tmp/workspace")
@@ -23,3 +30,50 @@ func TestSourceRootWithLeadingSlash(t *testing.T) {
 		t.Fatalf("sourceRoot() = %q, want %q", got, want)
 	}
 }
+
+func TestRetrieveNewIssueAssigneesFromAuthor(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/r/assignees/octocat":
+			w.WriteHeader(http.StatusNoContent)
+		case "/repos/o/r/assignees/alias":
+			w.WriteHeader(http.StatusNotFound)
+		case "/repos/o/r/commits/abc":
+			_, _ = w.Write([]byte(`{"sha":"abc","author":{"login":"committer"}}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:                     context.Background(),
+		client:                  newTestGitHubAPI(t, handler),
+		env:                     &env{codeOwner: "o", codeRepo: "r", issueOwner: "o", issueRepo: "r", assignFromAuthor: true},
+		tdg:                     tdglib.NewToDoGenerator(".", nil, nil, false, 0, 0, 1),
+		report:                  &report{},
+		assignables:             make(map[string]bool),
+		issueTitleToAssigneeMap: make(map[string]string),
+		commitToAuthorCache:     make(map[string]string),
+	}
+
+	comments := []*tdglib.ToDoComment{
+		{Title: "valid author", Author: "octocat", CommitHash: "abc"},
+		{Title: "invalid author", Author: "alias", CommitHash: "abc"},
+		{Title: "existing", Author: "octocat"},
+	}
+	issueMap := map[string]*github.Issue{"existing": {}}
+
+	s.wg.Add(1)
+	s.retrieveNewIssueAssignees(issueMap, comments)
+
+	want := map[string]string{"valid author": "octocat", "invalid author": "committer"}
+	if len(s.issueTitleToAssigneeMap) != len(want) {
+		t.Fatalf("retrieveNewIssueAssignees() = %v, want %v", s.issueTitleToAssigneeMap, want)
+	}
+
+	for title, login := range want {
+		if s.issueTitleToAssigneeMap[title] != login {
+			t.Fatalf("retrieveNewIssueAssignees()[%q] = %q, want %q", title, s.issueTitleToAssigneeMap[title], login)
+		}
+	}
+}
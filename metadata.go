@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	priorityMetadataKey  = "priority"
+	milestoneMetadataKey = "milestone"
+	labelsMetadataKey    = "labels"
+	assigneeMetadataKey  = "assignee"
+	labelPriorityPrefix  = "priority: "
+)
+
+// first four keys are parsed by tdg itself
+var knownMetadataKeys = map[string]bool{
+	"category":            true,
+	"issue":               true,
+	"estimate":            true,
+	"author":              true,
+	dueMetadataKey:        true,
+	snoozeMetadataKey:     true,
+	afterMetadataKey:      true,
+	priorityMetadataKey:   true,
+	milestoneMetadataKey:  true,
+	labelsMetadataKey:     true,
+	assigneeMetadataKey:   true,
+	discussionMetadataKey: true,
+}
+
+func isCommentRune(r rune) bool {
+	return r == '/' ||
+		r == '#' ||
+		r == '%' ||
+		r == ';' ||
+		r == '*'
+}
+
+// commentText strips comment symbols the same way tdg does
+// and returns false if the line is not a comment
+func commentText(line string) (string, bool) {
+	line = strings.TrimLeftFunc(line, unicode.IsSpace)
+	text := strings.TrimLeftFunc(line, isCommentRune)
+	if len(text) == len(line) {
+		return "", false
+	}
+
+	return strings.TrimSpace(text), true
+}
+
+// parseMetadata parses "key=value" pairs and returns nil
+// if the line contains anything else
+func parseMetadata(line string) map[string]string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string, len(fields))
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || len(key) == 0 {
+			return nil
+		}
+
+		metadata[strings.ToLower(key)] = value
+	}
+
+	return metadata
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines, scanner.Err()
+}
+
+// loadMetadata reads the line after the title of every comment since tdg
+// only keeps the few keys it knows about and treats the rest as body
+func (s *service) loadMetadata(comments []*tdglib.ToDoComment) {
+	files := make(map[string][]string)
+
+	for _, c := range comments {
+		lines, ok := files[c.File]
+		if !ok {
+			var err error
+			lines, err = readLines(filepath.Join(s.tdg.Root(), c.File))
+			if err != nil {
+				log.Printf("Error while reading metadata. file=%v err=%v", c.File, err)
+			}
+			files[c.File] = lines
+		}
+
+		// c.Line is 1-based so it points to the line after the title
+		if c.Line >= len(lines) {
+			continue
+		}
+
+		text, ok := commentText(lines[c.Line])
+		if !ok {
+			continue
+		}
+
+		metadata := parseMetadata(text)
+		if metadata == nil {
+			continue
+		}
+
+		// tdg keeps metadata line in the body when it has none of its own keys
+		if strings.HasPrefix(c.Body, text) {
+			c.Body = strings.TrimSpace(strings.TrimPrefix(c.Body, text))
+		}
+
+		s.metadata[c] = metadata
+	}
+
+	log.Printf("Loaded comments metadata. count=%v", len(s.metadata))
+}
+
+func (s *service) addMetadataProblem(c *tdglib.ToDoComment, problem string) {
+	log.Printf("Metadata problem. file=%v line=%v problem=%v", c.File, c.Line, problem)
+
+	s.metadataMux.Lock()
+	defer s.metadataMux.Unlock()
+
+	s.metadataProblems = append(s.metadataProblems,
+		fmt.Sprintf("| [%s:%v](%s) | %s |", markdownEscape(c.File), c.Line, s.createFileLink(c), markdownEscape(problem)))
+}
+
+func (s *service) checkUnknownMetadata(comments []*tdglib.ToDoComment) {
+	for _, c := range comments {
+		keys := make([]string, 0)
+		for key := range s.metadata[c] {
+			if !knownMetadataKeys[key] {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+		for _, key := range keys {
+			s.addMetadataProblem(c, fmt.Sprintf("unknown key `%v`", key))
+		}
+	}
+}
+
+func (s *service) reportMetadataProblems() {
+	s.metadataMux.Lock()
+	defer s.metadataMux.Unlock()
+
+	if len(s.metadataProblems) == 0 {
+		return
+	}
+
+	s.report.addSection("Metadata problems", "| Location | Problem |\n|---|---|\n"+strings.Join(s.metadataProblems, "\n"))
+}
+
+func (s *service) metadataLabels(c *tdglib.ToDoComment) []string {
+	metadata := s.metadata[c]
+	labels := make([]string, 0)
+
+	if priority := metadata[priorityMetadataKey]; len(priority) > 0 {
+		labels = append(labels, labelPriorityPrefix+strings.ToLower(priority))
+	}
+
+	for _, l := range strings.Split(metadata[labelsMetadataKey], ",") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			labels = append(labels, l)
+		}
+	}
+
+	return labels
+}
+
+// assignable checks if the user can be assigned to issues in the issue repo
+func (s *service) assignable(login string) bool {
+	s.metadataMux.Lock()
+	if ok, cached := s.assignables[login]; cached {
+		s.metadataMux.Unlock()
+		return ok
+	}
+	s.metadataMux.Unlock()
+
+	ok, _, err := s.client.isAssignee(s.ctx, s.env.issueOwner, s.env.issueRepo, login)
+	if err != nil {
+		log.Printf("Error while checking assignee. login=%v err=%v", login, err)
+		return false
+	}
+
+	s.metadataMux.Lock()
+	s.assignables[login] = ok
+	s.metadataMux.Unlock()
+
+	return ok
+}
+
+func (s *service) metadataAssignee(c *tdglib.ToDoComment) string {
+	assignee := strings.TrimPrefix(s.metadata[c][assigneeMetadataKey], "@")
+	if len(assignee) == 0 {
+		return ""
+	}
+
+	if !s.assignable(assignee) {
+		s.addMetadataProblem(c, fmt.Sprintf("`%v` cannot be assigned", assignee))
+		return ""
+	}
+
+	return assignee
+}
+
+// metadataMilestoneTitle returns title of the milestone from metadata or due date
+func (s *service) metadataMilestoneTitle(c *tdglib.ToDoComment) string {
+	if title := s.metadata[c][milestoneMetadataKey]; len(title) > 0 {
+		return title
+	}
+
+	if due, ok := s.dueDates[c]; ok && s.env.dueMilestones {
+		return dueMilestonePrefix + due.Format(dueDateLayout)
+	}
+
+	return ""
+}
+
+// metadataMilestone returns number of the milestone from metadata or due date
+func (s *service) metadataMilestone(c *tdglib.ToDoComment) (int, bool) {
+	if title := s.metadata[c][milestoneMetadataKey]; len(title) > 0 {
+		number, err := s.milestone(title, nil)
+		if err != nil {
+			log.Printf("Error while getting milestone. title=%v err=%v", title, err)
+			return 0, false
+		}
+
+		return number, true
+	}
+
+	if due, ok := s.dueDates[c]; ok && s.env.dueMilestones {
+		number, err := s.dueMilestone(due)
+		if err != nil {
+			log.Printf("Error while getting due milestone. due=%v err=%v", due.Format(dueDateLayout), err)
+			return 0, false
+		}
+
+		return number, true
+	}
+
+	return 0, false
+}
+
+// updateIssuesMetadata applies labels, milestone and assignee from
+// metadata to the existing issues when they were added to the comment later
+func (s *service) updateIssuesMetadata(issueMap map[string]*github.Issue, comments []*tdglib.ToDoComment) {
+	count := 0
+
+	for _, c := range comments {
+		if _, ok := s.metadata[c]; !ok {
+			continue
+		}
+
+		i, ok := issueMap[c.Title]
+		if !ok || i.GetState() == "closed" {
+			continue
+		}
+
+		req := &github.IssueRequest{}
+
+		labels := labelNames(i)
+		labelsChanged := false
+		for _, l := range s.metadataLabels(c) {
+			if !containsString(labels, l) {
+				labels = append(labels, l)
+				labelsChanged = true
+			}
+		}
+		if labelsChanged {
+			req.Labels = &labels
+		}
+
+		if title := s.metadataMilestoneTitle(c); len(title) > 0 && i.GetMilestone().GetTitle() != title {
+			if number, ok := s.metadataMilestone(c); ok {
+				req.Milestone = &number
+			}
+		}
+
+		if assignee := s.metadataAssignee(c); len(assignee) > 0 {
+			assignees := make([]string, 0, len(i.Assignees)+1)
+			for _, a := range i.Assignees {
+				assignees = append(assignees, a.GetLogin())
+			}
+			if !containsString(assignees, assignee) {
+				assignees = append(assignees, assignee)
+				req.Assignees = &assignees
+			}
+		}
+
+		if req.Labels == nil && req.Milestone == nil && req.Assignees == nil {
+			continue
+		}
+
+		log.Printf("About to update issue metadata. issue=%v", i.GetNumber())
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		edited, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), req)
+		if err != nil {
+			log.Printf("Error while updating issue metadata. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		i.Labels = edited.Labels
+		count++
+	}
+
+	log.Printf("Updated issues metadata. count=%v", count)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestParseMetadata(t *testing.T) {
+	cases := []struct {
+		line string
+		want map[string]string
+	}{
+		{line: "due=2024-01-01 Priority=high", want: map[string]string{"due": "2024-01-01", "priority": "high"}},
+		{line: "this is a=b body", want: nil},
+		{line: "", want: nil},
+		{line: "=value", want: nil},
+	}
+
+	for _, tc := range cases {
+		got := parseMetadata(tc.line)
+		if len(got) != len(tc.want) || (got == nil) != (tc.want == nil) {
+			t.Fatalf("parseMetadata(%q) = %v, want %v", tc.line, got, tc.want)
+		}
+
+		for k, v := range tc.want {
+			if got[k] != v {
+				t.Fatalf("parseMetadata(%q)[%v] = %q, want %q", tc.line, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestLoadMetadata(t *testing.T) {
+	root := t.TempDir()
+	source := "package main\n\n" +
+		"// TODO: first comment with metadata\n" +
+		"// due=2024-05-01\n" +
+		"// Body of the first comment\n" +
+		"func main() {}\n" +
+		"// TODO: second comment without metadata\n" +
+		"// just a body\n"
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte(source), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	first := &tdglib.ToDoComment{File: "main.go", Line: 3, Body: "due=2024-05-01\nBody of the first comment"}
+	second := &tdglib.ToDoComment{File: "main.go", Line: 7, Body: "just a body"}
+
+	s := &service{
+		tdg:      tdglib.NewToDoGenerator(root, nil, nil, false, 0, 0, 1),
+		metadata: make(map[*tdglib.ToDoComment]map[string]string),
+	}
+	s.loadMetadata([]*tdglib.ToDoComment{first, second})
+
+	if got := s.metadata[first]["due"]; got != "2024-05-01" {
+		t.Fatalf("loadMetadata() due = %q, want 2024-05-01", got)
+	}
+
+	if first.Body != "Body of the first comment" {
+		t.Fatalf("loadMetadata() body = %q, want metadata stripped", first.Body)
+	}
+
+	if _, ok := s.metadata[second]; ok || second.Body != "just a body" {
+		t.Fatalf("loadMetadata() parsed metadata of the second comment")
+	}
+}
+
+func TestUpdateIssuesMetadata(t *testing.T) {
+	var edit github.IssueRequest
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/o/r/assignees/octocat":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/repos/o/r/assignees/nobody":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/repos/o/r/milestones" && r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`[{"number":7,"title":"v3.2"}]`))
+		case r.URL.Path == "/repos/o/r/issues/1" && r.Method == http.MethodPatch:
+			_ = json.NewDecoder(r.Body).Decode(&edit)
+			_, _ = w.Write([]byte(`{"number":1}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	valid := &tdglib.ToDoComment{Title: "valid", File: "a.go", Line: 1}
+	invalid := &tdglib.ToDoComment{Title: "invalid", File: "a.go", Line: 5}
+
+	s := &service{
+		ctx:         context.Background(),
+		client:      newTestGitHubAPI(t, handler),
+		env:         &env{issueOwner: "o", issueRepo: "r"},
+		tdg:         tdglib.NewToDoGenerator(".", nil, nil, false, 0, 0, 1),
+		report:      &report{},
+		assignables: make(map[string]bool),
+		metadata: map[*tdglib.ToDoComment]map[string]string{
+			valid:   {"priority": "High", "labels": "perf,,api", "milestone": "v3.2", "assignee": "octocat"},
+			invalid: {"assignee": "nobody", "colour": "red"},
+		},
+	}
+
+	issueMap := map[string]*github.Issue{
+		"valid":   {Number: github.Ptr(1), Labels: []*github.Label{{Name: github.Ptr("api")}}},
+		"invalid": {Number: github.Ptr(2)},
+	}
+
+	todos := []*tdglib.ToDoComment{valid, invalid}
+	s.checkUnknownMetadata(todos)
+	s.updateIssuesMetadata(issueMap, todos)
+	s.reportMetadataProblems()
+
+	if edit.Labels == nil || strings.Join(*edit.Labels, ",") != "api,priority: high,perf" {
+		t.Fatalf("updateIssuesMetadata() labels = %v", edit.Labels)
+	}
+
+	if edit.GetMilestone() != 7 {
+		t.Fatalf("updateIssuesMetadata() milestone = %v, want 7", edit.GetMilestone())
+	}
+
+	if edit.Assignees == nil || strings.Join(*edit.Assignees, ",") != "octocat" {
+		t.Fatalf("updateIssuesMetadata() assignees = %v", edit.Assignees)
+	}
+
+	summary := s.report.String()
+	for _, want := range []string{"unknown key `colour`", "`nobody` cannot be assigned"} {
+		if !strings.Contains(summary, want) {
+			t.Fatalf("reportMetadataProblems() does not contain %q:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+)
+
+const labelMappingSeparator = "->"
+
+var errLabelMigration = errors.New("label migration is not complete")
+
+// labelMapping renames a label or every label with the prefix
+type labelMapping struct {
+	from   string
+	to     string
+	prefix bool
+}
+
+// parseLabelMappings parses "old -> new" lines, prefixes end with "*"
+func parseLabelMappings(value string) ([]*labelMapping, error) {
+	mappings := make([]*labelMapping, 0)
+
+	for _, line := range strings.Split(value, "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		// prefixes keep trailing spaces before "*" like "branch: *"
+		from, to, ok := strings.Cut(line, labelMappingSeparator)
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || len(from) == 0 || len(to) == 0 {
+			return nil, fmt.Errorf("invalid label mapping %q", line)
+		}
+
+		fromPrefix, fromOk := strings.CutSuffix(from, labelWildcard)
+		toPrefix, toOk := strings.CutSuffix(to, labelWildcard)
+		if fromOk != toOk {
+			return nil, fmt.Errorf("both labels must be prefixes in %q", line)
+		}
+
+		m := &labelMapping{from: from, to: to}
+		if fromOk {
+			m = &labelMapping{from: fromPrefix, to: toPrefix, prefix: true}
+		}
+
+		if prefix, ok := m.renamesBuiltinPrefix(); ok {
+			return nil, fmt.Errorf("label prefix %q is built in and cannot be changed in %q", prefix, line)
+		}
+
+		mappings = append(mappings, m)
+	}
+
+	return mappings, nil
+}
+
+// builtinLabelPrefixes are added by the action and it would keep creating them
+var builtinLabelPrefixes = []string{
+	labelBranchPrefix,
+	labelTypePrefix,
+	labelAreaPrefix,
+	labelLangPrefix,
+	labelEstimatePrefix,
+	labelAgePrefix,
+	labelPriorityPrefix,
+}
+
+// renamesBuiltinPrefix checks if labels with a built-in prefix lose it,
+// e.g. issues without "branch: " label would ignore CLOSE_ON_SAME_BRANCH
+func (m *labelMapping) renamesBuiltinPrefix() (string, bool) {
+	for _, prefix := range builtinLabelPrefixes {
+		if strings.HasPrefix(m.from, prefix) && !strings.HasPrefix(m.to, prefix) {
+			return prefix, true
+		}
+
+		// shorter prefixes like "bra*" rename built-in prefixes too
+		if renamed, ok := m.rename(prefix); ok && m.prefix && !strings.HasPrefix(renamed, prefix) {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+func (m *labelMapping) rename(name string) (string, bool) {
+	if !m.prefix {
+		return m.to, name == m.from
+	}
+
+	suffix, ok := strings.CutPrefix(name, m.from)
+	return m.to + suffix, ok
+}
+
+func renameLabel(mappings []*labelMapping, name string) (string, bool) {
+	for _, m := range mappings {
+		if renamed, ok := m.rename(name); ok {
+			return renamed, true
+		}
+	}
+
+	return name, false
+}
+
+// migratedLabels returns labels of the issue after migration
+func migratedLabels(mappings []*labelMapping, labels []string) ([]string, bool) {
+	result := make([]string, 0, len(labels))
+	changed := false
+
+	for _, l := range labels {
+		renamed, ok := renameLabel(mappings, l)
+		changed = changed || ok
+		if !containsString(result, renamed) {
+			result = append(result, renamed)
+		}
+	}
+
+	return result, changed
+}
+
+func (s *service) listIssuesWithLabel(name string) ([]*github.Issue, error) {
+	var all []*github.Issue
+
+	opt := &github.IssueListByRepoOptions{
+		Labels:      []string{name},
+		State:       "all",
+		ListOptions: github.ListOptions{PerPage: defaultIssuesPerPage},
+	}
+
+	for {
+		issues, resp, err := s.client.listByRepo(s.ctx, s.env.issueOwner, s.env.issueRepo, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range issues {
+			if !i.IsPullRequest() {
+				all = append(all, i)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.ListOptions.Page = resp.NextPage
+	}
+
+	return all, nil
+}
+
+// migrateLabels relabels all issues with old labels and verifies that
+// no issue has them anymore so that the next sync finds all issues
+func (s *service) migrateLabels() error {
+	mappings, err := parseLabelMappings(s.env.migrateLabels)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.listAllLabels()
+	if err != nil {
+		return err
+	}
+
+	oldLabels := make([]string, 0)
+	for _, l := range existing {
+		if _, ok := renameLabel(mappings, l.GetName()); ok {
+			oldLabels = append(oldLabels, l.GetName())
+		}
+	}
+	sort.Strings(oldLabels)
+
+	issues := make(map[int]*github.Issue)
+	for _, name := range oldLabels {
+		labelIssues, err := s.listIssuesWithLabel(name)
+		if err != nil {
+			return err
+		}
+
+		for _, i := range labelIssues {
+			issues[i.GetNumber()] = i
+		}
+	}
+
+	numbers := make([]int, 0, len(issues))
+	for number := range issues {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	migrated := 0
+	for _, number := range numbers {
+		labels, changed := migratedLabels(mappings, labelNames(issues[number]))
+		if !changed {
+			continue
+		}
+
+		log.Printf("About to migrate issue labels. issue=%v labels=%v", number, labels)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		req := &github.IssueRequest{Labels: &labels}
+		if _, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, number, req); err != nil {
+			log.Printf("Error while migrating issue labels. issue=%v err=%v", number, err)
+			continue
+		}
+
+		migrated++
+	}
+
+	log.Printf("Migrated issue labels. count=%v total=%v", migrated, len(issues))
+
+	var b strings.Builder
+	b.WriteString("| Old label | New label | Issues | Remaining |\n|---|---|---|---|\n")
+
+	incomplete := 0
+	for _, name := range oldLabels {
+		renamed, _ := renameLabel(mappings, name)
+
+		count := 0
+		for _, i := range issues {
+			if containsString(labelNames(i), name) {
+				count++
+			}
+		}
+
+		// in dry run mode nothing is verified since nothing was changed
+		remaining := "-"
+		if !s.env.dryRun {
+			left, err := s.listIssuesWithLabel(name)
+			if err != nil {
+				return err
+			}
+
+			incomplete += len(left)
+			remaining = fmt.Sprint(len(left))
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %v | %s |\n", markdownEscape(name), markdownEscape(renamed), count, remaining)
+	}
+
+	title := "Label migration"
+	if s.env.dryRun {
+		title += " (dry run)"
+	}
+	if renamed, ok := renameLabel(mappings, s.env.label); ok {
+		fmt.Fprintf(&b, "\nChange `LABEL` from `%s` to `%s` before the next sync.\n", s.env.label, renamed)
+	}
+
+	s.report.addSection(title, b.String())
+
+	if incomplete > 0 {
+		return fmt.Errorf("%w: %v issues still have old labels", errLabelMigration, incomplete)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+)
+
+func TestParseLabelMappings(t *testing.T) {
+	mappings, err := parseLabelMappings("todo comment -> tech-debt\n\nbranch: feature -> branch: main\ndebt: * -> tech-debt: *\n")
+	if err != nil {
+		t.Fatalf("parseLabelMappings() error = %v", err)
+	}
+
+	want := []*labelMapping{
+		{from: "todo comment", to: "tech-debt"},
+		{from: "branch: feature", to: "branch: main"},
+		{from: "debt: ", to: "tech-debt: ", prefix: true},
+	}
+	if !reflect.DeepEqual(mappings, want) {
+		t.Fatalf("parseLabelMappings() = %+v, want %+v", mappings, want)
+	}
+
+	labels, changed := migratedLabels(mappings, []string{"todo comment", "branch: feature", "debt: high", "bug", "tech-debt"})
+	if !changed || !reflect.DeepEqual(labels, []string{"tech-debt", "branch: main", "tech-debt: high", "bug"}) {
+		t.Fatalf("migratedLabels() = %v, %v", labels, changed)
+	}
+
+	invalid := []string{
+		"todo",
+		"type: * -> kind",
+		" -> new",
+		"branch: * -> branch/*",
+		"branch: feat* -> feat/*",
+		"bra* -> b*",
+		"type: todo -> todo",
+	}
+	for _, invalid := range invalid {
+		if _, err := parseLabelMappings(invalid); err == nil {
+			t.Fatalf("parseLabelMappings(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestMigrateLabels(t *testing.T) {
+	migrated := make(map[string][]string)
+	listed := make(map[string]int)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/labels":
+			_, _ = w.Write([]byte(`[{"name":"todo comment"},{"name":"debt: high"},{"name":"bug"}]`))
+		case "GET /repos/o/r/issues":
+			label := r.URL.Query().Get("labels")
+			listed[label]++
+			if listed[label] > 1 {
+				// verification after migration
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			switch label {
+			case "todo comment":
+				_, _ = w.Write([]byte(`[
+					{"number":1,"labels":[{"name":"todo comment"},{"name":"debt: high"}]},
+					{"number":2,"labels":[{"name":"todo comment"}],"pull_request":{}}
+				]`))
+			case "debt: high":
+				_, _ = w.Write([]byte(`[{"number":1,"labels":[{"name":"todo comment"},{"name":"debt: high"}]}]`))
+			}
+		case "PATCH /repos/o/r/issues/1":
+			var req github.IssueRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			migrated[r.URL.Path] = req.GetLabels()
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{issueOwner: "o", issueRepo: "r", label: "todo comment", migrateLabels: "todo comment -> tech-debt\ndebt: * -> tech-debt: *"},
+		report: &report{},
+	}
+
+	if err := s.migrateLabels(); err != nil {
+		t.Fatalf("migrateLabels() error = %v", err)
+	}
+
+	want := map[string][]string{"/repos/o/r/issues/1": {"tech-debt", "tech-debt: high"}}
+	if !reflect.DeepEqual(migrated, want) {
+		t.Fatalf("migrateLabels() = %v, want %v", migrated, want)
+	}
+
+	if summary := s.report.String(); !strings.Contains(summary, "| todo comment | tech-debt | 1 | 0 |") ||
+		!strings.Contains(summary, "Change `LABEL` from `todo comment` to `tech-debt`") {
+		t.Fatalf("migrateLabels() report = %v", summary)
+	}
+}
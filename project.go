@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+// fields are matched by name and missing ones are skipped
+const (
+	projectStatusField       = "Status"
+	projectEstimateField     = "Estimate"
+	projectAreaField         = "Area"
+	projectTypeField         = "Type"
+	projectFileField         = "File"
+	defaultProjectStatus     = "Todo"
+	defaultProjectDoneStatus = "Done"
+)
+
+var errProjectNotFound = errors.New("project not found")
+
+const projectQuery = `query($owner: String!, $number: Int!) {
+  repositoryOwner(login: $owner) {
+    ... on ProjectV2Owner {
+      projectV2(number: $number) {
+        id
+        fields(first: 100) {
+          nodes {
+            ... on ProjectV2FieldCommon { id name dataType }
+            ... on ProjectV2SingleSelectField { options { id name } }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const addProjectItemMutation = `mutation($project: ID!, $content: ID!) {
+  addProjectV2ItemById(input: {projectId: $project, contentId: $content}) {
+    item { id }
+  }
+}`
+
+const updateProjectFieldMutation = `mutation($project: ID!, $item: ID!, $field: ID!, $value: ProjectV2FieldValue!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field, value: $value}) {
+    projectV2Item { id }
+  }
+}`
+
+const issueProjectItemsQuery = `query($issue: ID!) {
+  node(id: $issue) {
+    ... on Issue {
+      projectItems(first: 50) {
+        nodes { id project { id } }
+      }
+    }
+  }
+}`
+
+type projectOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type projectField struct {
+	ID       string           `json:"id"`
+	Name     string           `json:"name"`
+	DataType string           `json:"dataType"`
+	Options  []*projectOption `json:"options"`
+}
+
+type project struct {
+	id     string
+	fields map[string]*projectField
+}
+
+// value converts text to the GraphQL field value of the field type
+func (f *projectField) value(text string) (map[string]interface{}, error) {
+	switch f.DataType {
+	case "SINGLE_SELECT":
+		for _, o := range f.Options {
+			if strings.EqualFold(o.Name, text) {
+				return map[string]interface{}{"singleSelectOptionId": o.ID}, nil
+			}
+		}
+		return nil, fmt.Errorf("no option %q", text)
+	case "NUMBER":
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"number": number}, nil
+	case "TEXT":
+		return map[string]interface{}{"text": text}, nil
+	case "DATE":
+		return map[string]interface{}{"date": text}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported field type %v", f.DataType)
+}
+
+func (s *service) loadProject() error {
+	var result struct {
+		RepositoryOwner *struct {
+			ProjectV2 *struct {
+				ID     string `json:"id"`
+				Fields struct {
+					Nodes []*projectField `json:"nodes"`
+				} `json:"fields"`
+			} `json:"projectV2"`
+		} `json:"repositoryOwner"`
+	}
+
+	variables := map[string]interface{}{"owner": s.env.projectOwner, "number": s.env.projectNumber}
+	if err := s.client.graphQL(s.ctx, "project", projectQuery, variables, &result); err != nil {
+		return err
+	}
+
+	if result.RepositoryOwner == nil || result.RepositoryOwner.ProjectV2 == nil {
+		return errProjectNotFound
+	}
+
+	p := &project{
+		id:     result.RepositoryOwner.ProjectV2.ID,
+		fields: make(map[string]*projectField),
+	}
+
+	for _, f := range result.RepositoryOwner.ProjectV2.Fields.Nodes {
+		// fields of other types come as empty objects
+		if len(f.ID) > 0 {
+			p.fields[strings.ToLower(f.Name)] = f
+		}
+	}
+
+	log.Printf("Loaded project. owner=%v number=%v fields=%v", s.env.projectOwner, s.env.projectNumber, len(p.fields))
+	s.project = p
+
+	return nil
+}
+
+func (s *service) setProjectField(itemID, name, text string) {
+	if len(text) == 0 {
+		return
+	}
+
+	field, ok := s.project.fields[strings.ToLower(name)]
+	if !ok {
+		return
+	}
+
+	value, err := field.value(text)
+	if err != nil {
+		log.Printf("Cannot set project field. field=%v value=%v err=%v", name, text, err)
+		return
+	}
+
+	variables := map[string]interface{}{
+		"project": s.project.id,
+		"item":    itemID,
+		"field":   field.ID,
+		"value":   value,
+	}
+	if err := s.client.graphQL(s.ctx, "update_project_field", updateProjectFieldMutation, variables, nil); err != nil {
+		log.Printf("Error while setting project field. item=%v field=%v err=%v", itemID, name, err)
+	}
+}
+
+// addToProject adds the issue to the project and fills fields from the comment
+func (s *service) addToProject(issue *github.Issue, c *tdglib.ToDoComment) {
+	var result struct {
+		AddProjectV2ItemByID struct {
+			Item struct {
+				ID string `json:"id"`
+			} `json:"item"`
+		} `json:"addProjectV2ItemById"`
+	}
+
+	variables := map[string]interface{}{"project": s.project.id, "content": issue.GetNodeID()}
+	if err := s.client.graphQL(s.ctx, "add_project_item", addProjectItemMutation, variables, &result); err != nil {
+		log.Printf("Error while adding issue to the project. issue=%v err=%v", issue.GetNumber(), err)
+		return
+	}
+
+	itemID := result.AddProjectV2ItemByID.Item.ID
+	log.Printf("Added issue to the project. issue=%v item=%v", issue.GetNumber(), itemID)
+
+	estimate := ""
+	if c.Estimate > 0 {
+		estimate = strconv.FormatFloat(c.Estimate, 'f', -1, 64)
+	}
+
+	s.setProjectField(itemID, projectStatusField, s.env.projectStatus)
+	s.setProjectField(itemID, projectEstimateField, estimate)
+	s.setProjectField(itemID, projectAreaField, c.Category)
+	s.setProjectField(itemID, projectTypeField, c.Type)
+	s.setProjectField(itemID, projectFileField, s.env.repoPath(c.File))
+}
+
+// completeProjectItem moves project item of the closed issue to done status
+func (s *service) completeProjectItem(i *github.Issue) {
+	var result struct {
+		Node *struct {
+			ProjectItems struct {
+				Nodes []struct {
+					ID      string `json:"id"`
+					Project struct {
+						ID string `json:"id"`
+					} `json:"project"`
+				} `json:"nodes"`
+			} `json:"projectItems"`
+		} `json:"node"`
+	}
+
+	variables := map[string]interface{}{"issue": i.GetNodeID()}
+	if err := s.client.graphQL(s.ctx, "issue_project_items", issueProjectItemsQuery, variables, &result); err != nil {
+		log.Printf("Error while getting project items. issue=%v err=%v", i.GetNumber(), err)
+		return
+	}
+
+	if result.Node == nil {
+		return
+	}
+
+	for _, item := range result.Node.ProjectItems.Nodes {
+		if item.Project.ID == s.project.id {
+			s.setProjectField(item.ID, projectStatusField, s.env.projectDoneStatus)
+			log.Printf("Completed project item. issue=%v item=%v", i.GetNumber(), item.ID)
+		}
+	}
+}
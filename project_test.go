@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestProjectFieldValue(t *testing.T) {
+	status := &projectField{
+		ID:       "status",
+		DataType: "SINGLE_SELECT",
+		Options:  []*projectOption{{ID: "1", Name: "Todo"}, {ID: "2", Name: "Done"}},
+	}
+
+	value, err := status.value("done")
+	if err != nil || value["singleSelectOptionId"] != "2" {
+		t.Fatalf("value() = %v, %v", value, err)
+	}
+
+	if _, err := status.value("Blocked"); err == nil {
+		t.Fatalf("value() expected error for unknown option")
+	}
+
+	estimate := &projectField{ID: "estimate", DataType: "NUMBER"}
+	value, err = estimate.value("1.5")
+	if err != nil || value["number"] != 1.5 {
+		t.Fatalf("value() = %v, %v", value, err)
+	}
+}
+
+func TestAddToProject(t *testing.T) {
+	updates := make(map[string]interface{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+			return
+		}
+
+		var req graphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Decode() error = %v", err)
+			return
+		}
+
+		switch req.Query {
+		case projectQuery:
+			_, _ = w.Write([]byte(`{"data":{"repositoryOwner":{"projectV2":{"id":"P","fields":{"nodes":[
+				{"id":"F1","name":"Status","dataType":"SINGLE_SELECT","options":[{"id":"O1","name":"Todo"}]},
+				{"id":"F2","name":"Estimate","dataType":"NUMBER"},
+				{"id":"F3","name":"File","dataType":"TEXT"},
+				{}
+			]}}}}}`))
+		case addProjectItemMutation:
+			if req.Variables["content"] != "I_1" {
+				t.Errorf("addProjectV2ItemById content = %v", req.Variables["content"])
+			}
+			_, _ = w.Write([]byte(`{"data":{"addProjectV2ItemById":{"item":{"id":"ITEM"}}}}`))
+		case updateProjectFieldMutation:
+			updates[req.Variables["field"].(string)] = req.Variables["value"]
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		default:
+			t.Errorf("unexpected query %v", req.Query)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{issueOwner: "o", projectOwner: "o", projectNumber: 1, projectStatus: "Todo"},
+	}
+
+	if err := s.loadProject(); err != nil {
+		t.Fatalf("loadProject() error = %v", err)
+	}
+
+	nodeID := "I_1"
+	s.addToProject(&github.Issue{NodeID: &nodeID}, &tdglib.ToDoComment{File: "main.go", Estimate: 2, Category: "ignored"})
+
+	want := map[string]interface{}{
+		"F1": map[string]interface{}{"singleSelectOptionId": "O1"},
+		"F2": map[string]interface{}{"number": float64(2)},
+		"F3": map[string]interface{}{"text": "main.go"},
+	}
+	if len(updates) != len(want) {
+		t.Fatalf("addToProject() updates = %v, want %v", updates, want)
+	}
+	for field, value := range want {
+		got, _ := json.Marshal(updates[field])
+		expected, _ := json.Marshal(value)
+		if string(got) != string(expected) {
+			t.Fatalf("addToProject() field %v = %s, want %s", field, got, expected)
+		}
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":null,"errors":[{"message":"Could not resolve to a node"}]}`))
+	})
+
+	api := newTestGitHubAPI(t, handler)
+	err := api.graphQL(context.Background(), "test", "query { viewer { login } }", nil, nil)
+	if err == nil || err.Error() != "graphql error: Could not resolve to a node" {
+		t.Fatalf("graphQL() error = %v", err)
+	}
+}
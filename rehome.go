@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+)
+
+// branchMerged checks if the branch is deleted or all its commits are
+// already in the base branch
+func (s *service) branchMerged(base, branch string) (bool, error) {
+	exists, err := s.branchExists(branch)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		return true, nil
+	}
+
+	comparison, _, err := s.client.compareCommits(s.ctx, s.env.codeOwner, s.env.codeRepo, base, branch)
+	if err != nil {
+		return false, err
+	}
+
+	status := comparison.GetStatus()
+
+	return status == "identical" || status == "behind", nil
+}
+
+// rehomeMergedBranches moves open issues of merged or deleted branches to
+// the default branch so that CLOSE_ON_SAME_BRANCH can close them there
+func (s *service) rehomeMergedBranches(issues []*github.Issue) {
+	repo, _, err := s.client.getRepository(s.ctx, s.env.codeOwner, s.env.codeRepo)
+	if err != nil {
+		log.Printf("Error while getting repository. err=%v", err)
+		return
+	}
+
+	defaultBranch := repo.GetDefaultBranch()
+	if defaultBranch != s.env.branch {
+		log.Printf("Skipping re-homing issues for non-default branch. branch=%v default=%v", s.env.branch, defaultBranch)
+		return
+	}
+
+	defaultLabel := labelBranchPrefix + defaultBranch
+	merged := make(map[string]bool)
+	rehomed := make(map[string]int)
+
+	for _, i := range issues {
+		if i.GetState() == "closed" {
+			continue
+		}
+
+		labels := make([]string, 0, len(i.Labels))
+		changed := false
+
+		for _, name := range labelNames(i) {
+			branch, ok := strings.CutPrefix(name, labelBranchPrefix)
+			if ok && branch != defaultBranch {
+				isMerged, checked := merged[branch]
+				if !checked {
+					isMerged, err = s.branchMerged(defaultBranch, branch)
+					if err != nil {
+						// not cached so that other issues of the branch retry the check
+						log.Printf("Error while checking branch. branch=%v err=%v", branch, err)
+					} else {
+						merged[branch] = isMerged
+					}
+				}
+
+				if isMerged {
+					rehomed[branch]++
+					name, changed = defaultLabel, true
+				}
+			}
+
+			if !containsString(labels, name) {
+				labels = append(labels, name)
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		log.Printf("About to re-home an issue. issue=%v labels=%v", i.GetNumber(), labels)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		req := &github.IssueRequest{Labels: &labels}
+		if _, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), req); err != nil {
+			log.Printf("Error while re-homing an issue. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		// closing later relies on the new branch label
+		i.Labels = make([]*github.Label, 0, len(labels))
+		for _, name := range labels {
+			i.Labels = append(i.Labels, &github.Label{Name: github.Ptr(name)})
+		}
+	}
+
+	log.Printf("Re-homed issues of merged branches. branches=%v", len(rehomed))
+
+	if len(rehomed) == 0 {
+		return
+	}
+
+	branches := make([]string, 0, len(rehomed))
+	for branch := range rehomed {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+
+	title := "Re-homed issues"
+	if s.env.dryRun {
+		title += " (dry run)"
+	}
+
+	lines := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		lines = append(lines, fmt.Sprintf("- `%s` → `%s`: %v", branch, defaultBranch, rehomed[branch]))
+	}
+
+	s.report.addSection(title, strings.Join(lines, "\n"))
+}
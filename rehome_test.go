@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+)
+
+func TestRehomeMergedBranches(t *testing.T) {
+	edited := make(map[string][]string)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r":
+			_, _ = w.Write([]byte(`{"default_branch":"main"}`))
+		case "GET /repos/o/r/git/ref/heads/deleted":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "GET /repos/o/r/git/ref/heads/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message":"Server Error"}`))
+		case "GET /repos/o/r/git/ref/heads/merged", "GET /repos/o/r/git/ref/heads/open":
+			_, _ = w.Write([]byte(`{}`))
+		case "GET /repos/o/r/compare/main...merged":
+			_, _ = w.Write([]byte(`{"status":"behind"}`))
+		case "GET /repos/o/r/compare/main...open":
+			_, _ = w.Write([]byte(`{"status":"diverged"}`))
+		case "PATCH /repos/o/r/issues/1", "PATCH /repos/o/r/issues/2":
+			var req github.IssueRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			edited[r.URL.Path] = req.GetLabels()
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "r", issueOwner: "o", issueRepo: "r", branch: "main", closeOnSameBranch: true},
+		report: &report{},
+	}
+
+	newIssue := func(number int, state string, labels ...string) *github.Issue {
+		i := &github.Issue{Number: github.Ptr(number), State: github.Ptr(state)}
+		for _, l := range labels {
+			i.Labels = append(i.Labels, &github.Label{Name: github.Ptr(l)})
+		}
+		return i
+	}
+
+	issues := []*github.Issue{
+		newIssue(1, "open", "todo", "branch: deleted"),
+		newIssue(2, "open", "branch: merged", "branch: main"),
+		newIssue(3, "open", "todo", "branch: open"),
+		newIssue(4, "closed", "branch: deleted"),
+		newIssue(5, "open", "branch: main"),
+		newIssue(6, "open", "todo", "branch: broken"),
+	}
+
+	s.rehomeMergedBranches(issues)
+
+	want := map[string][]string{
+		"/repos/o/r/issues/1": {"todo", "branch: main"},
+		"/repos/o/r/issues/2": {"branch: main"},
+	}
+	if !reflect.DeepEqual(edited, want) {
+		t.Fatalf("rehomeMergedBranches() edited = %v, want %v", edited, want)
+	}
+
+	if !s.canCloseIssue(issues[0]) || s.canCloseIssue(issues[2]) || s.canCloseIssue(issues[5]) {
+		t.Fatalf("canCloseIssue() after re-homing = %v, %v, %v", s.canCloseIssue(issues[0]), s.canCloseIssue(issues[2]), s.canCloseIssue(issues[5]))
+	}
+
+	if len(s.report.sections) != 1 {
+		t.Fatalf("rehomeMergedBranches() report sections = %v", len(s.report.sections))
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+)
+
+type removalCommit struct {
+	sha         string
+	authorName  string
+	authorEmail string
+}
+
+// findRemovingCommit finds the latest commit between base and head that
+// changed the number of occurrences of the text in the file (relative to
+// the repository root) or anywhere when the path is empty, which for a
+// comment that is gone is the commit that removed it
+func findRemovingCommit(root, text, path, base, head string) (*removalCommit, error) {
+	if len(head) == 0 {
+		head = "HEAD"
+	}
+
+	revision := head
+	if len(base) > 0 {
+		revision = base + ".." + head
+	}
+
+	args := []string{"log", "-n", "1", "--format=%H%x1f%an%x1f%ae", "-S", text, revision, "--"}
+	if len(path) > 0 {
+		args = append(args, ":(top,literal)"+path)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	fields := strings.Split(strings.TrimSpace(string(out)), "\x1f")
+	if len(fields) != 3 {
+		return nil, nil
+	}
+
+	return &removalCommit{sha: fields[0], authorName: fields[1], authorEmail: fields[2]}, nil
+}
+
+// issueFilePath extracts path of the file from the link in the issue body
+func (s *service) issueFilePath(i *github.Issue) string {
+	pattern := fmt.Sprintf(`https://github\.com/%s/%s/blob/[^/\s]+/([^#\s]+)#L`,
+		regexp.QuoteMeta(s.env.codeOwner), regexp.QuoteMeta(s.env.codeRepo))
+
+	m := regexp.MustCompile(pattern).FindStringSubmatch(i.GetBody())
+	if m == nil {
+		return ""
+	}
+
+	path, err := url.PathUnescape(m[1])
+	if err != nil {
+		return ""
+	}
+
+	return path
+}
+
+// removingCommit searches the history since the last processed commit and
+// falls back to the whole history when base commit was not fetched
+func (s *service) removingCommit(title, path string) *removalCommit {
+	s.markSafeDirectory()
+
+	commit, err := findRemovingCommit(s.tdg.Root(), title, path, s.env.baseSHA, s.env.sha)
+	if err != nil && len(s.env.baseSHA) > 0 {
+		log.Printf("Error while searching removing commit since base. base=%v err=%v", s.env.baseSHA, err)
+		commit, err = findRemovingCommit(s.tdg.Root(), title, path, "", s.env.sha)
+	}
+
+	if err != nil {
+		log.Printf("Error while searching removing commit. title=%v err=%v", title, err)
+		return nil
+	}
+
+	return commit
+}
+
+// codeRef formats reference to the commit or pull request in the code
+// repository so that it is rendered as a link in the issue repository
+func (s *service) codeRef(separator, ref string) string {
+	if (s.env.codeRepo != s.env.issueRepo) || (s.env.codeOwner != s.env.issueOwner) {
+		return fmt.Sprintf("%s/%s%s%s", s.env.codeOwner, s.env.codeRepo, separator, ref)
+	}
+
+	if separator == "#" {
+		return "#" + ref
+	}
+
+	return ref
+}
+
+// issueRef formats reference to the issue so that it is rendered
+// as a link in the code repository
+func (s *service) issueRef(i *github.Issue) string {
+	if (s.env.codeRepo != s.env.issueRepo) || (s.env.codeOwner != s.env.issueOwner) {
+		return fmt.Sprintf("%s/%s#%v", s.env.issueOwner, s.env.issueRepo, i.GetNumber())
+	}
+
+	return fmt.Sprintf("#%v", i.GetNumber())
+}
+
+// commitPullRequest prefers the pull request merged into the current branch
+// since the same commit can be part of many pull requests. Open or closed
+// pull requests did not resolve anything and are ignored
+func (s *service) commitPullRequest(sha string) *github.PullRequest {
+	pulls, _, err := s.client.listPullRequestsWithCommit(s.ctx, s.env.codeOwner, s.env.codeRepo, sha, &github.ListOptions{})
+	if err != nil {
+		log.Printf("Error while listing pull requests of the commit. sha=%v err=%v", sha, err)
+		return nil
+	}
+
+	var merged *github.PullRequest
+	for _, pr := range pulls {
+		if pr.MergedAt == nil {
+			continue
+		}
+
+		if pr.GetBase().GetRef() == s.env.branch {
+			return pr
+		}
+
+		if merged == nil {
+			merged = pr
+		}
+	}
+
+	return merged
+}
+
+// closingComment credits the commit that removed the comment, its author
+// and the pull request the commit came from
+func (s *service) closingComment(i *github.Issue) (string, *github.PullRequest) {
+	sha := s.env.sha
+	author := ""
+
+	// the title can appear in docs or tests, so only the file of the comment is searched
+	if commit := s.removingCommit(i.GetTitle(), s.issueFilePath(i)); commit != nil {
+		sha = commit.sha
+		author = commit.authorName
+
+		// mentions are only possible for commits linked to an account
+		if c, _, err := s.client.getCommit(s.ctx, s.env.codeOwner, s.env.codeRepo, sha, &github.ListOptions{}); err != nil {
+			log.Printf("Error while getting removing commit. sha=%v err=%v", sha, err)
+		} else if login := c.GetAuthor().GetLogin(); len(login) > 0 {
+			author = "@" + login
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Closed in commit %v", s.codeRef("@", sha))
+
+	if len(author) > 0 {
+		fmt.Fprintf(&b, " by %v", author)
+	}
+
+	pr := s.commitPullRequest(sha)
+	if pr != nil {
+		fmt.Fprintf(&b, " in %v", s.codeRef("#", fmt.Sprint(pr.GetNumber())))
+	}
+
+	return b.String(), pr
+}
+
+// commentPullRequests adds one cross-reference per pull request
+// listing all TODO issues it resolved
+func (s *service) commentPullRequests(resolved map[int][]*github.Issue) {
+	numbers := make([]int, 0, len(resolved))
+	for number := range resolved {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	for _, number := range numbers {
+		refs := make([]string, 0, len(resolved[number]))
+		for _, i := range resolved[number] {
+			refs = append(refs, s.issueRef(i))
+		}
+
+		body := "This PR resolved TODO issue " + refs[0]
+		if len(refs) > 1 {
+			body = "This PR resolved TODO issues " + strings.Join(refs, ", ")
+		}
+
+		comment := &github.IssueComment{Body: &body}
+		if _, _, err := s.client.createComment(s.ctx, s.env.codeOwner, s.env.codeRepo, number, comment); err != nil {
+			log.Printf("Error while commenting on pull request. pr=%v err=%v", number, err)
+			continue
+		}
+
+		log.Printf("Added a comment to the pull request. pr=%v issues=%v", number, len(refs))
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestClosingComment(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+
+	root := t.TempDir()
+	git := func(author string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME="+author, "GIT_AUTHOR_EMAIL="+author+"@example.com",
+			"GIT_COMMITTER_NAME="+author, "GIT_COMMITTER_EMAIL="+author+"@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v error = %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(author, file, source string) string {
+		if err := os.WriteFile(filepath.Join(root, file), []byte(source), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		git(author, "add", file)
+		git(author, "commit", "-q", "-m", "change")
+		return git(author, "rev-parse", "HEAD")
+	}
+
+	git("jane", "init", "-q")
+	base := commit("jane", "main.go", "package main\n// TODO: remove me\n")
+	removing := commit("jack", "main.go", "package main\n")
+	docs := commit("jill", "NOTES.md", "We should remove me later\n")
+	head := commit("jill", "main.go", "package main\n\nfunc main() {}\n")
+
+	found, err := findRemovingCommit(root, "remove me", "main.go", base, head)
+	if err != nil {
+		t.Fatalf("findRemovingCommit() error = %v", err)
+	}
+	if found == nil || found.sha != removing || found.authorName != "jack" {
+		t.Fatalf("findRemovingCommit() = %+v, want %v", found, removing)
+	}
+
+	// without the path any commit with the text matches
+	if found, err = findRemovingCommit(root, "remove me", "", base, head); err != nil || found == nil || found.sha != docs {
+		t.Fatalf("findRemovingCommit() without path = %+v, %v, want %v", found, err, docs)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/code/commits/" + removing:
+			_, _ = w.Write([]byte(`{"sha":"` + removing + `","author":{"login":"jack-gh"}}`))
+		case "/repos/o/code/commits/" + removing + "/pulls":
+			_, _ = w.Write([]byte(`[{"number":41},{"number":43,"merged_at":"2024-01-01T00:00:00Z","base":{"ref":"feature"}},{"number":42,"merged_at":"2024-01-01T00:00:00Z","base":{"ref":"main"}}]`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "code", issueOwner: "o", issueRepo: "issues", sha: head, baseSHA: "bad", branch: "main"},
+		tdg:    tdglib.NewToDoGenerator(root, nil, nil, false, 0, 0, 1),
+	}
+
+	title := "remove me"
+	body := "Line: 2\nhttps://github.com/o/code/blob/" + base + "/main.go#L0-L5"
+	if path := s.issueFilePath(&github.Issue{Body: &body}); path != "main.go" {
+		t.Fatalf("issueFilePath() = %q, want main.go", path)
+	}
+
+	got, pr := s.closingComment(&github.Issue{Title: &title, Body: &body})
+	want := "Closed in commit o/code@" + removing + " by @jack-gh in o/code#42"
+	if got != want || pr.GetNumber() != 42 {
+		t.Fatalf("closingComment() = %q, %v, want %q", got, pr.GetNumber(), want)
+	}
+}
+
+func TestCommitPullRequestNotMerged(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"number":41,"state":"open"},{"number":40,"state":"closed","base":{"ref":"main"}}]`))
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "r", branch: "main"},
+	}
+
+	if pr := s.commitPullRequest("sha"); pr != nil {
+		t.Fatalf("commitPullRequest() = %v, want nil", pr.GetNumber())
+	}
+}
+
+func TestCommentPullRequests(t *testing.T) {
+	bodies := make(map[string]string)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var comment github.IssueComment
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			t.Errorf("Decode() error = %v", err)
+		}
+		bodies[r.URL.Path] = comment.GetBody()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "r", issueOwner: "o", issueRepo: "r"},
+	}
+
+	first, second, third := 1, 2, 3
+	s.commentPullRequests(map[int][]*github.Issue{
+		10: {{Number: &first}, {Number: &second}},
+		20: {{Number: &third}},
+	})
+
+	want := map[string]string{
+		"/repos/o/r/issues/10/comments": "This PR resolved TODO issues #1, #2",
+		"/repos/o/r/issues/20/comments": "This PR resolved TODO issue #3",
+	}
+	if len(bodies) != len(want) {
+		t.Fatalf("commentPullRequests() = %v, want %v", bodies, want)
+	}
+	for path, body := range want {
+		if bodies[path] != body {
+			t.Fatalf("commentPullRequests()[%v] = %q, want %q", path, bodies[path], body)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// report collects markdown sections for the job summary
+type report struct {
+	mux      sync.Mutex
+	sections []string
+}
+
+func (r *report) addSection(title, body string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.sections = append(r.sections, fmt.Sprintf("### %s\n\n%s\n", title, strings.TrimSpace(body)))
+}
+
+func (r *report) String() string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if len(r.sections) == 0 {
+		return ""
+	}
+
+	return "## TODO comments\n\n" + strings.Join(r.sections, "\n")
+}
+
+func (r *report) write() error {
+	content := r.String()
+	if len(content) == 0 {
+		return nil
+	}
+
+	summary := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summary == "" {
+		log.Printf("GITHUB_STEP_SUMMARY is not set. Printing the report.\n%s", content)
+		return nil
+	}
+
+	f, err := os.OpenFile(summary, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, content)
+
+	return err
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	snoozeMetadataKey = "snooze"
+	afterMetadataKey  = "after"
+)
+
+// tagExists checks the tag in the code repository since tags are
+// often not fetched by the checkout
+func (s *service) tagExists(tag string) (bool, error) {
+	if exists, ok := s.tags[tag]; ok {
+		return exists, nil
+	}
+
+	_, _, err := s.client.getRef(s.ctx, s.env.codeOwner, s.env.codeRepo, "tags/"+tag)
+	if err != nil && !isNotFoundGitHubError(err) {
+		return false, err
+	}
+
+	s.tags[tag] = err == nil
+
+	return err == nil, nil
+}
+
+// snoozeReason returns why the comment should not become an issue yet
+func (s *service) snoozeReason(c *tdglib.ToDoComment) (string, error) {
+	until, ok, err := s.metadataDate(c, snoozeMetadataKey)
+	if err != nil {
+		return "", err
+	}
+
+	if ok && s.now.Before(until) {
+		return "until " + until.Format(dueDateLayout), nil
+	}
+
+	if tag, ok := s.metadata[c][afterMetadataKey]; ok && len(tag) > 0 {
+		exists, err := s.tagExists(tag)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return "until tag " + tag, nil
+		}
+	}
+
+	return "", nil
+}
+
+// resolveSnoozed has to be called before issues are processed in parallel.
+// Snoozed comments stay in the list so that existing issues are not closed
+func (s *service) resolveSnoozed(comments []*tdglib.ToDoComment) {
+	var b strings.Builder
+
+	for _, c := range comments {
+		reason, err := s.snoozeReason(c)
+		if err != nil {
+			log.Printf("Error while checking snooze. file=%v line=%v err=%v", c.File, c.Line, err)
+			continue
+		}
+
+		if len(reason) == 0 {
+			continue
+		}
+
+		s.snoozed[c] = true
+		fmt.Fprintf(&b, "| %s | %s | [%s:%v](%s) |\n",
+			markdownEscape(c.Title), reason, markdownEscape(c.File), c.Line, s.createFileLink(c))
+	}
+
+	log.Printf("Resolved snoozed comments. count=%v", len(s.snoozed))
+
+	if len(s.snoozed) > 0 {
+		s.report.addSection("Snoozed TODOs", "| Title | Snoozed | Location |\n|---|---|---|\n"+b.String())
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestResolveSnoozed(t *testing.T) {
+	requests := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/repos/o/r/git/ref/tags/v1.0":
+			_, _ = w.Write([]byte(`{"ref":"refs/tags/v1.0"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	})
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := &tdglib.ToDoComment{Title: "future", File: "a.go", Line: 1}
+	past := &tdglib.ToDoComment{Title: "past", File: "a.go", Line: 2}
+	released := &tdglib.ToDoComment{Title: "released", File: "a.go", Line: 3}
+	unreleased := &tdglib.ToDoComment{Title: "unreleased", File: "a.go", Line: 4}
+	unreleasedToo := &tdglib.ToDoComment{Title: "unreleased too", File: "a.go", Line: 5}
+
+	s := &service{
+		ctx:     context.Background(),
+		client:  newTestGitHubAPI(t, handler),
+		env:     &env{codeOwner: "o", codeRepo: "r"},
+		tdg:     tdglib.NewToDoGenerator(".", nil, nil, false, 0, 0, 1),
+		now:     now,
+		report:  &report{},
+		snoozed: make(map[*tdglib.ToDoComment]bool),
+		tags:    make(map[string]bool),
+		metadata: map[*tdglib.ToDoComment]map[string]string{
+			future:        {"snooze": "2024-02-01"},
+			past:          {"snooze": "2023-12-01"},
+			released:      {"after": "v1.0"},
+			unreleased:    {"after": "v2.0"},
+			unreleasedToo: {"after": "v2.0"},
+		},
+	}
+
+	s.resolveSnoozed([]*tdglib.ToDoComment{future, past, released, unreleased, unreleasedToo})
+
+	want := map[*tdglib.ToDoComment]bool{future: true, unreleased: true, unreleasedToo: true}
+	if len(s.snoozed) != len(want) {
+		t.Fatalf("resolveSnoozed() = %v, want %v", s.snoozed, want)
+	}
+
+	for c := range want {
+		if !s.snoozed[c] {
+			t.Fatalf("resolveSnoozed() did not snooze %q", c.Title)
+		}
+	}
+
+	if requests != 2 {
+		t.Fatalf("resolveSnoozed() requests = %d, want tags to be cached", requests)
+	}
+}
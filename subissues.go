@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+var (
+	parentIssueRE = regexp.MustCompile(`(?m)^Parent issue: #(\d+)\n`)
+	issueLineRE   = regexp.MustCompile(`(?m)^Line: \d+`)
+)
+
+func parentIssueLine(parent int) string {
+	return fmt.Sprintf("Parent issue: #%v\n", parent)
+}
+
+// parentFromBody returns the parent issue written into the issue body
+func parentFromBody(body string) int {
+	m := parentIssueRE.FindStringSubmatch(body)
+	if m == nil {
+		return 0
+	}
+
+	parent, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+
+	return parent
+}
+
+// withParent replaces, adds or removes the parent issue line of the body
+func withParent(body string, parent int) string {
+	line := ""
+	if parent > 0 {
+		line = parentIssueLine(parent)
+	}
+
+	if loc := parentIssueRE.FindStringIndex(body); loc != nil {
+		return body[:loc[0]] + line + body[loc[1]:]
+	}
+
+	if parent == 0 {
+		return body
+	}
+
+	if loc := issueLineRE.FindStringIndex(body); loc != nil {
+		return body[:loc[0]] + line + body[loc[0]:]
+	}
+
+	return body + "\n" + line
+}
+
+// parentIssueExists checks the issue repo for the parent and caches the result
+func (s *service) parentIssueExists(number int) (bool, error) {
+	s.metadataMux.Lock()
+	exists, ok := s.parents[number]
+	s.metadataMux.Unlock()
+	if ok {
+		return exists, nil
+	}
+
+	issue, _, err := s.client.getIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, number)
+	if err != nil && !isNotFoundGitHubError(err) {
+		return false, err
+	}
+
+	exists = err == nil && !issue.IsPullRequest()
+
+	s.metadataMux.Lock()
+	s.parents[number] = exists
+	s.metadataMux.Unlock()
+
+	return exists, nil
+}
+
+// commentParent returns the parent issue of the comment, invalid
+// parents are reported and ignored when sub-issues are used. Errors
+// are returned since the parent can be valid after all
+func (s *service) commentParent(c *tdglib.ToDoComment) (int, error) {
+	if c.Issue <= 0 || !s.env.subIssues {
+		return c.Issue, nil
+	}
+
+	exists, err := s.parentIssueExists(c.Issue)
+	if err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		s.addMetadataProblem(c, fmt.Sprintf("parent issue #%v does not exist", c.Issue))
+		return 0, nil
+	}
+
+	return c.Issue, nil
+}
+
+func (s *service) attachSubIssue(parent int, i *github.Issue) error {
+	req := github.SubIssueRequest{SubIssueID: i.GetID(), ReplaceParent: github.Ptr(true)}
+	if _, _, err := s.client.addSubIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, parent, req); err != nil {
+		return err
+	}
+
+	log.Printf("Attached sub-issue. parent=%v issue=%v", parent, i.GetNumber())
+
+	return nil
+}
+
+// updateSubIssues moves existing issues to the parent from the comment
+// when it was added, changed or removed since the issue was created
+func (s *service) updateSubIssues(issueMap map[string]*github.Issue, comments []*tdglib.ToDoComment) {
+	count := 0
+
+	for _, c := range comments {
+		i, ok := issueMap[c.Title]
+		if !ok || i.GetState() == "closed" {
+			continue
+		}
+
+		parent, err := s.commentParent(c)
+		if err != nil {
+			log.Printf("Error while checking parent issue. issue=%v err=%v", c.Issue, err)
+			continue
+		}
+
+		current := parentFromBody(i.GetBody())
+		if current == parent {
+			continue
+		}
+
+		log.Printf("About to change parent issue. issue=%v from=%v to=%v", i.GetNumber(), current, parent)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		if current > 0 {
+			req := github.SubIssueRequest{SubIssueID: i.GetID()}
+			_, err := s.client.removeSubIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, current, req)
+			if err != nil && !isNotFoundGitHubError(err) {
+				log.Printf("Error while detaching sub-issue. parent=%v issue=%v err=%v", current, i.GetNumber(), err)
+				continue
+			}
+		}
+
+		if parent > 0 {
+			if err := s.attachSubIssue(parent, i); err != nil {
+				log.Printf("Error while attaching sub-issue. parent=%v issue=%v err=%v", parent, i.GetNumber(), err)
+				continue
+			}
+		}
+
+		body := withParent(i.GetBody(), parent)
+		edited, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), &github.IssueRequest{Body: &body})
+		if err != nil {
+			log.Printf("Error while updating parent issue. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		i.Body = edited.Body
+		count++
+	}
+
+	log.Printf("Updated parent issues. count=%v", count)
+}
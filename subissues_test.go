@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestWithParent(t *testing.T) {
+	tests := []struct {
+		body   string
+		parent int
+		want   string
+	}{
+		{"text\n\nLine: 3\nlink", 5, "text\n\nParent issue: #5\nLine: 3\nlink"},
+		{"text\n\nParent issue: #5\nLine: 3\nlink", 7, "text\n\nParent issue: #7\nLine: 3\nlink"},
+		{"text\n\nParent issue: #5\nLine: 3\nlink", 0, "text\n\nLine: 3\nlink"},
+		{"text", 0, "text"},
+		{"text", 2, "text\nParent issue: #2\n"},
+	}
+
+	for _, tt := range tests {
+		got := withParent(tt.body, tt.parent)
+		if got != tt.want {
+			t.Fatalf("withParent(%q, %v) = %q, want %q", tt.body, tt.parent, got, tt.want)
+		}
+
+		if parentFromBody(got) != tt.parent {
+			t.Fatalf("parentFromBody(%q) = %v, want %v", got, parentFromBody(got), tt.parent)
+		}
+	}
+}
+
+func TestUpdateSubIssues(t *testing.T) {
+	requests := make([]string, 0)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/o/r/issues/7":
+			_, _ = w.Write([]byte(`{"number":7}`))
+		case "GET /repos/o/r/issues/8":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "GET /repos/o/r/issues/9":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message":"Server Error"}`))
+		case "DELETE /repos/o/r/issues/5/sub_issue":
+			_, _ = w.Write([]byte(`{}`))
+		case "POST /repos/o/r/issues/7/sub_issues":
+			var req github.SubIssueRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SubIssueID != 100 {
+				t.Errorf("sub-issue request = %+v, err = %v", req, err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		case "PATCH /repos/o/r/issues/1":
+			var req github.IssueRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			_ = json.NewEncoder(w).Encode(&github.Issue{Body: req.Body})
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:     context.Background(),
+		client:  newTestGitHubAPI(t, handler),
+		env:     &env{issueOwner: "o", issueRepo: "r", subIssues: true},
+		tdg:     tdglib.NewToDoGenerator(".", nil, nil, false, 0, 0, 1),
+		report:  &report{},
+		parents: make(map[int]bool),
+	}
+
+	moved := &github.Issue{ID: github.Ptr(int64(100)), Number: github.Ptr(1), Body: github.Ptr("todo\n\nParent issue: #5\nLine: 1\n")}
+	invalid := &github.Issue{ID: github.Ptr(int64(200)), Number: github.Ptr(2), Body: github.Ptr("todo\n\nLine: 1\n")}
+	failed := &github.Issue{ID: github.Ptr(int64(300)), Number: github.Ptr(3), Body: github.Ptr("todo\n\nParent issue: #5\nLine: 1\n")}
+	issueMap := map[string]*github.Issue{"moved": moved, "invalid": invalid, "failed": failed}
+
+	s.updateSubIssues(issueMap, []*tdglib.ToDoComment{
+		{Title: "moved", Issue: 7},
+		{Title: "invalid", Issue: 8},
+		{Title: "failed", Issue: 9},
+	})
+
+	// errors while checking the parent do not detach the issue
+	if failed.GetBody() != "todo\n\nParent issue: #5\nLine: 1\n" {
+		t.Fatalf("updateSubIssues() body after error = %q", failed.GetBody())
+	}
+
+	if moved.GetBody() != "todo\n\nParent issue: #7\nLine: 1\n" {
+		t.Fatalf("updateSubIssues() body = %q", moved.GetBody())
+	}
+
+	if len(s.metadataProblems) != 1 {
+		t.Fatalf("updateSubIssues() problems = %v", s.metadataProblems)
+	}
+
+	if len(requests) != 6 {
+		t.Fatalf("updateSubIssues() requests = %v", requests)
+	}
+}
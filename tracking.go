@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+const (
+	trackingByFile        = "file"
+	trackingByDir         = "dir"
+	trackingByCategory    = "category"
+	trackingByType        = "type"
+	trackingTitlePrefix   = "TODOs: "
+	trackingMarkerFormat  = "<!-- tdg-tracking: %s -->"
+	trackingUncategorized = "uncategorized"
+	taskOpenPrefix        = "- [ ] "
+	taskDonePrefix        = "- [x] "
+)
+
+func isTrackingMode(mode string) bool {
+	switch mode {
+	case trackingByFile, trackingByDir, trackingByCategory, trackingByType:
+		return true
+	}
+
+	return false
+}
+
+// trackingKey returns the group of the comment in the tracking mode
+func (e *env) trackingKey(c *tdglib.ToDoComment) string {
+	switch e.trackingIssues {
+	case trackingByDir:
+		return path.Dir(e.repoPath(c.File))
+	case trackingByCategory:
+		if len(c.Category) == 0 {
+			return trackingUncategorized
+		}
+		return c.Category
+	case trackingByType:
+		return strings.ToUpper(c.Type)
+	}
+
+	return e.repoPath(c.File)
+}
+
+func taskText(c *tdglib.ToDoComment) string {
+	return fmt.Sprintf("%s: %s", strings.ToUpper(c.Type), markdownEscape(c.Title))
+}
+
+// parseTasks returns items of the task list with the prefix and without
+// links since links point to the commit of the run and change every time
+func parseTasks(body string) []string {
+	tasks := make([]string, 0)
+
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasPrefix(line, taskOpenPrefix) && !strings.HasPrefix(line, taskDonePrefix) {
+			continue
+		}
+
+		if idx := strings.LastIndex(line, " (["); idx >= 0 {
+			line = line[:idx]
+		}
+
+		tasks = append(tasks, line)
+	}
+
+	return tasks
+}
+
+// parseOpenTasks returns text of unchecked items of the task list without links
+func parseOpenTasks(body string) []string {
+	tasks := make([]string, 0)
+
+	for _, task := range parseTasks(body) {
+		if text, ok := strings.CutPrefix(task, taskOpenPrefix); ok {
+			tasks = append(tasks, text)
+		}
+	}
+
+	return tasks
+}
+
+// trackingBody regenerates the task list. Items removed since the previous
+// run are checked off once and dropped on the next run
+func (s *service) trackingBody(key, previous string, comments []*tdglib.ToDoComment) string {
+	current := make(map[string]bool, len(comments))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, trackingMarkerFormat+"\n", s.env.trackingIssues)
+	fmt.Fprintf(&b, "TODO comments in `%s`.\n\n", key)
+
+	for _, c := range comments {
+		text := taskText(c)
+		current[text] = true
+		fmt.Fprintf(&b, "%s%s ([%s:%v](%s))\n", taskOpenPrefix, text, markdownEscape(s.env.repoPath(c.File)), c.Line, s.createFileLink(c))
+	}
+
+	for _, text := range parseOpenTasks(previous) {
+		if !current[text] {
+			fmt.Fprintf(&b, "%s%s\n", taskDonePrefix, text)
+		}
+	}
+
+	return b.String()
+}
+
+func (s *service) trackingLabels() []string {
+	labels := []string{s.env.label}
+	if s.env.extendedLabels {
+		labels = append(labels, labelBranchPrefix+s.env.branch)
+	}
+
+	return labels
+}
+
+// updateTrackingIssues maintains one issue per group of comments instead
+// of one issue per comment and closes the issue when its group is empty
+func (s *service) updateTrackingIssues(issues []*github.Issue, comments []*tdglib.ToDoComment) {
+	marker := fmt.Sprintf(trackingMarkerFormat, s.env.trackingIssues)
+
+	tracked := make(map[string]*github.Issue)
+	for _, i := range issues {
+		if strings.Contains(i.GetBody(), marker) && strings.HasPrefix(i.GetTitle(), trackingTitlePrefix) {
+			tracked[strings.TrimPrefix(i.GetTitle(), trackingTitlePrefix)] = i
+		}
+	}
+
+	groups := make(map[string][]*tdglib.ToDoComment)
+	for _, c := range comments {
+		if s.snoozed[c] {
+			continue
+		}
+
+		key := s.env.trackingKey(c)
+		groups[key] = append(groups[key], c)
+	}
+
+	keys := make([]string, 0, len(groups)+len(tracked))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	for key := range tracked {
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		group := groups[key]
+		sort.SliceStable(group, func(a, b int) bool {
+			if group[a].File != group[b].File {
+				return group[a].File < group[b].File
+			}
+			return group[a].Line < group[b].Line
+		})
+
+		i, exists := tracked[key]
+		body := s.trackingBody(key, i.GetBody(), group)
+
+		if !exists {
+			s.createTrackingIssue(key, body)
+			continue
+		}
+
+		state := "open"
+		if len(group) == 0 {
+			state = "closed"
+			if !s.canCloseIssue(i) {
+				log.Printf("Cannot close the tracking issue. issue=%v", i.GetNumber())
+				continue
+			}
+		}
+
+		// the body is only rewritten when items were added, removed or checked off
+		if strings.Join(parseTasks(body), "\n") == strings.Join(parseTasks(i.GetBody()), "\n") && state == i.GetState() {
+			continue
+		}
+
+		log.Printf("About to update a tracking issue. issue=%v items=%v state=%v", i.GetNumber(), len(group), state)
+
+		if s.env.dryRun {
+			log.Printf("Dry run mode.")
+			continue
+		}
+
+		req := &github.IssueRequest{Body: &body, State: &state}
+		if _, _, err := s.client.editIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, i.GetNumber(), req); err != nil {
+			log.Printf("Error while updating a tracking issue. issue=%v err=%v", i.GetNumber(), err)
+			continue
+		}
+
+		if state == "closed" && i.GetState() != "closed" {
+			s.closedCount++
+		}
+	}
+
+	log.Printf("Updated tracking issues. groups=%v created=%v closed=%v", len(keys), s.createdCount, s.closedCount)
+}
+
+func (s *service) createTrackingIssue(key, body string) {
+	title := trackingTitlePrefix + key
+	log.Printf("About to create a tracking issue. title=%v", title)
+
+	if s.env.dryRun {
+		log.Printf("Dry run mode.")
+		return
+	}
+
+	if s.env.addLimit > 0 && s.createdCount >= s.env.addLimit {
+		log.Printf("Exceeded limit of issues to create. limit=%v", s.env.addLimit)
+		return
+	}
+
+	labels := s.trackingLabels()
+	req := &github.IssueRequest{Title: &title, Body: &body, Labels: &labels}
+	issue, _, err := s.client.createIssue(s.ctx, s.env.issueOwner, s.env.issueRepo, req)
+	if err != nil {
+		log.Printf("Error while creating a tracking issue. title=%v err=%v", title, err)
+		return
+	}
+
+	log.Printf("Created a tracking issue. title=%v issue=%v", title, issue.GetNumber())
+	s.createdCount++
+}
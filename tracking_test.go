@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v73/github"
+	"gitlab.com/ribtoks/tdg/pkg/tdglib"
+)
+
+func TestTrackingKey(t *testing.T) {
+	c := &tdglib.ToDoComment{Type: "fixme", File: "src/app/main.go"}
+
+	tests := map[string]string{
+		trackingByFile:     "src/app/main.go",
+		trackingByDir:      "src/app",
+		trackingByCategory: trackingUncategorized,
+		trackingByType:     "FIXME",
+	}
+
+	for mode, want := range tests {
+		e := &env{trackingIssues: mode}
+		if got := e.trackingKey(c); got != want {
+			t.Fatalf("trackingKey(%v) = %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestParseOpenTasks(t *testing.T) {
+	body := "<!-- tdg-tracking: file -->\n\n" +
+		"- [ ] TODO: first ([a.go:1](https://github.com/o/r/blob/sha/a.go#L1-L3))\r\n" +
+		"- [x] TODO: removed\n" +
+		"- [ ] FIXME: (second) ([a.go:5](link))\n"
+
+	got := parseOpenTasks(body)
+	want := []string{"TODO: first", "FIXME: (second)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseOpenTasks() = %v, want %v", got, want)
+	}
+}
+
+func TestUpdateTrackingIssues(t *testing.T) {
+	created := make([]*github.IssueRequest, 0)
+	edited := make(map[string]*github.IssueRequest)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req github.IssueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Decode() error = %v", err)
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /repos/o/r/issues":
+			created = append(created, &req)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":10}`))
+		case "PATCH /repos/o/r/issues/1", "PATCH /repos/o/r/issues/2":
+			edited[r.URL.Path] = &req
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	s := &service{
+		ctx:    context.Background(),
+		client: newTestGitHubAPI(t, handler),
+		env:    &env{codeOwner: "o", codeRepo: "r", issueOwner: "o", issueRepo: "r", label: "todo", sha: "sha", trackingIssues: trackingByFile},
+		tdg:    tdglib.NewToDoGenerator(".", nil, nil, false, 0, 0, 1),
+	}
+
+	issues := []*github.Issue{
+		{
+			Number: github.Ptr(1),
+			State:  github.Ptr("open"),
+			Title:  github.Ptr("TODOs: a.go"),
+			Body:   github.Ptr("<!-- tdg-tracking: file -->\n- [ ] TODO: kept ([a.go:1](x))\n- [ ] TODO: removed ([a.go:2](x))\n"),
+		},
+		{
+			Number: github.Ptr(2),
+			State:  github.Ptr("open"),
+			Title:  github.Ptr("TODOs: b.go"),
+			Body:   github.Ptr("<!-- tdg-tracking: file -->\n- [ ] TODO: gone ([b.go:1](x))\n"),
+		},
+		{
+			Number: github.Ptr(3),
+			State:  github.Ptr("open"),
+			Title:  github.Ptr("TODOs: c.go"),
+			Body:   github.Ptr("<!-- tdg-tracking: dir -->\n"),
+		},
+		{
+			// links of the previous run point to another commit
+			Number: github.Ptr(4),
+			State:  github.Ptr("open"),
+			Title:  github.Ptr("TODOs: e.go"),
+			Body:   github.Ptr("<!-- tdg-tracking: file -->\n- [ ] TODO: same ([e.go:3](https://github.com/o/r/blob/old/e.go#L0-L5))\n"),
+		},
+	}
+
+	comments := []*tdglib.ToDoComment{
+		{Type: "TODO", Title: "kept", File: "a.go", Line: 1},
+		{Type: "TODO", Title: "new", File: "d.go", Line: 4},
+		{Type: "TODO", Title: "same", File: "e.go", Line: 5},
+	}
+
+	s.updateTrackingIssues(issues, comments)
+
+	if len(created) != 1 || created[0].GetTitle() != "TODOs: d.go" || !strings.Contains(created[0].GetBody(), "- [ ] TODO: new ([d.go:4]") {
+		t.Fatalf("updateTrackingIssues() created = %+v", created)
+	}
+
+	first := edited["/repos/o/r/issues/1"]
+	if first == nil || first.GetState() != "open" ||
+		!strings.Contains(first.GetBody(), "- [ ] TODO: kept ([a.go:1]") ||
+		!strings.Contains(first.GetBody(), "- [x] TODO: removed\n") {
+		t.Fatalf("updateTrackingIssues() edited first = %+v", first)
+	}
+
+	second := edited["/repos/o/r/issues/2"]
+	if second == nil || second.GetState() != "closed" || !strings.Contains(second.GetBody(), "- [x] TODO: gone\n") {
+		t.Fatalf("updateTrackingIssues() edited second = %+v", second)
+	}
+
+	if s.createdCount != 1 || s.closedCount != 1 {
+		t.Fatalf("updateTrackingIssues() created = %v closed = %v", s.createdCount, s.closedCount)
+	}
+}